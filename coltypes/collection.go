@@ -0,0 +1,256 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Names and affixes of collection data types as they're reported by the information_schema,
+	// e.g. "integer[]" or "array" for arrays, "integer list" for lists and "map[text=>integer]" for maps.
+	arrayDataType = "array"
+	arraySuffix   = "[]"
+	listDataType  = "list"
+	listSuffix    = " list"
+	mapDataType   = "map"
+	mapPrefix     = "map["
+)
+
+const (
+	// literalNull is a NULL element of a collection literal.
+	literalNull = "NULL"
+	// mapKeySeparator separates keys and values of a map literal.
+	mapKeySeparator = "=>"
+)
+
+// literalEscaper escapes the characters that have a special meaning within quoted elements of collection literals.
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// lookupConverter returns the converter of the data type,
+// recognising array, list and map data types by their names.
+func lookupConverter(dataType string) (converter, bool) {
+	if convert, ok := converters[dataType]; ok {
+		return convert, true
+	}
+
+	switch {
+	case isListDataType(dataType):
+		return parseList, true
+	case isMapDataType(dataType):
+		return parseMap, true
+	default:
+		return nil, false
+	}
+}
+
+// isListDataType reports whether the data type is an array or a list data type.
+func isListDataType(dataType string) bool {
+	return dataType == arrayDataType || strings.HasSuffix(dataType, arraySuffix) ||
+		dataType == listDataType || strings.HasSuffix(dataType, listSuffix)
+}
+
+// isMapDataType reports whether the data type is a map data type.
+func isMapDataType(dataType string) bool {
+	return dataType == mapDataType || strings.HasPrefix(dataType, mapPrefix)
+}
+
+// parseList encodes a slice, or a JSON array string, as an array or a list literal, e.g. {1,"a",{2,3}}.
+// Nested slices are encoded as nested arrays or lists. Other strings are considered to be literals already.
+// JSON documents are handled the same way as strings.
+func parseList(value any) (any, error) {
+	if document, ok := value.(JSON); ok {
+		value = string(document)
+	}
+
+	if s, ok := value.(string); ok {
+		decoded, isJSON := decodeJSONString(s, '[')
+		if !isJSON {
+			return s, nil
+		}
+
+		value = decoded
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+	}
+
+	return encodeListLiteral(items)
+}
+
+// parseMap encodes a map, or a JSON object string, as a map literal, e.g. {a=>1,b=>{c=>2}}.
+// Nested maps are encoded as nested map literals. Other strings are considered to be literals already.
+// JSON documents are handled the same way as strings.
+func parseMap(value any) (any, error) {
+	if document, ok := value.(JSON); ok {
+		value = string(document)
+	}
+
+	if s, ok := value.(string); ok {
+		decoded, isJSON := decodeJSONString(s, '{')
+		if !isJSON {
+			return s, nil
+		}
+
+		value = decoded
+	}
+
+	entries, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+	}
+
+	return encodeMapLiteral(entries)
+}
+
+// decodeJSONString decodes a string containing a JSON value that starts with the delimiter.
+// It reports false if the string doesn't contain such a value.
+func decodeJSONString(s string, delimiter byte) (any, bool) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" || trimmed[0] != delimiter {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, false
+	}
+
+	return decoded, true
+}
+
+// encodeListLiteral encodes the items as an array or a list literal.
+func encodeListLiteral(items []any) (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, item := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if nested, ok := item.([]any); ok {
+			encoded, err := encodeListLiteral(nested)
+			if err != nil {
+				return "", err
+			}
+
+			buf.WriteString(encoded)
+
+			continue
+		}
+
+		encoded, err := encodeLiteralElement(item)
+		if err != nil {
+			return "", fmt.Errorf("encode item %d: %w", i, err)
+		}
+
+		buf.WriteString(encoded)
+	}
+	buf.WriteByte('}')
+
+	return buf.String(), nil
+}
+
+// encodeMapLiteral encodes the entries as a map literal with keys in alphabetical order.
+func encodeMapLiteral(entries map[string]any) (string, error) {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(quoteLiteralElement(key))
+		buf.WriteString(mapKeySeparator)
+
+		var (
+			encoded string
+			err     error
+		)
+
+		switch v := entries[key].(type) {
+		case map[string]any:
+			encoded, err = encodeMapLiteral(v)
+		case []any:
+			encoded, err = encodeListLiteral(v)
+		default:
+			encoded, err = encodeLiteralElement(v)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("encode value %q: %w", key, err)
+		}
+
+		buf.WriteString(encoded)
+	}
+	buf.WriteByte('}')
+
+	return buf.String(), nil
+}
+
+// encodeLiteralElement encodes a scalar element of a collection literal.
+// Objects within lists are encoded as quoted JSON.
+func encodeLiteralElement(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return literalNull, nil
+	case string:
+		return quoteLiteralElement(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return quoteLiteralElement(v.Format(time.RFC3339Nano)), nil
+	}
+
+	if i, ok := toInt64(value); ok {
+		return strconv.FormatInt(i, 10), nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("marshal %T: %w", value, err)
+	}
+
+	return quoteLiteralElement(string(encoded)), nil
+}
+
+// quoteLiteralElement quotes an element of a collection literal.
+func quoteLiteralElement(s string) string {
+	return `"` + literalEscaper.Replace(s) + `"`
+}
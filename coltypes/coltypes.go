@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/conduitio/conduit-commons/opencdc"
@@ -30,13 +31,20 @@ const (
 
 	// timeDataTypeLayout is a time format for the TIME data type.
 	timeDataTypeLayout = "15:04:05"
+
+	// unknownColumnRecheckInterval is how long a column confirmed to be missing is trusted to stay missing,
+	// after that the column types are refreshed once again in case the column was added.
+	unknownColumnRecheckInterval = time.Minute
 )
 
 var (
 	// querySchemaColumnTypes is a query that selects column names and
 	// their data and column types from the information_schema.
+	// Tables not qualified with a schema or a database are looked up in the current ones.
 	querySchemaColumnTypes = "select column_name, data_type " +
-		"from information_schema.columns where table_name = $1;"
+		"from information_schema.columns where table_name = $1 " +
+		"and table_schema = coalesce(nullif($2, ''), current_schema()) " +
+		"and table_catalog = coalesce(nullif($3, ''), current_database());"
 )
 
 // Querier is a database querier interface needed for the GetColumnTypes function.
@@ -46,42 +54,58 @@ type Querier interface {
 
 // ConvertStructureData converts an sdk.StructureData values to a proper database types
 // based on the provided columnTypes.
-// For now it converts just TIME values.
+// Values of columns with data types that have no converter, and nil values, are kept as is.
+// Nested objects and arrays are encoded according to the data type, see lookupNestedConverter.
+// A value that can't be converted results in a *ConversionError naming the field and the data type.
 func ConvertStructureData(
 	_ context.Context, columnTypes map[string]string, data opencdc.StructuredData,
 ) (opencdc.StructuredData, error) {
 	result := make(opencdc.StructuredData, len(data))
 
 	for key, value := range data {
-		if value == nil {
+		convert, ok := lookupConverter(columnTypes[key])
+		if isNested(value) {
+			convert, ok = lookupNestedConverter(columnTypes[key]), true
+		}
+
+		if !ok || value == nil {
 			result[key] = value
 
 			continue
 		}
 
-		switch columnTypes[key] {
-		case timeDataType:
-			parsedValue, err := parseTime(value)
-			if err != nil {
-				return opencdc.StructuredData{}, fmt.Errorf("parse time: %w", err)
+		parsedValue, err := convert(value)
+		if err != nil {
+			return opencdc.StructuredData{}, &ConversionError{
+				Field: key,
+				Type:  columnTypes[key],
+				Err:   err,
 			}
-
-			result[key] = parsedValue
-
-		default:
-			result[key] = value
 		}
+
+		result[key] = parsedValue
 	}
 
 	return result, nil
 }
 
 // GetColumnTypes returns a map containing all table's columns and their database types.
+//
+// The tableName may be qualified as schema.table or database.schema.table,
+// unqualified parts default to the current schema and database of the session.
+// Both the tableName and the returned column names are matched exactly as they are stored in the catalog,
+// so callers must look up the names with the same identifier case as the one used to write them.
 func GetColumnTypes(ctx context.Context, querier Querier, tableName string) (map[string]string, error) {
-	rows, err := querier.Query(ctx, querySchemaColumnTypes, tableName)
+	name, err := ParseTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := querier.Query(ctx, querySchemaColumnTypes, name.Name, name.Schema, name.Database)
 	if err != nil {
 		return nil, fmt.Errorf("query column types: %w", err)
 	}
+	defer rows.Close()
 
 	columnTypes := make(map[string]string)
 	for rows.Next() {
@@ -93,9 +117,142 @@ func GetColumnTypes(ctx context.Context, querier Querier, tableName string) (map
 		columnTypes[columnName] = strings.ToLower(dataType)
 	}
 
+	// errors of the query itself, e.g. a dropped connection, are reported only once the rows are read
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read rows: %w", err)
+	}
+
+	return columnTypes, nil
+}
+
+// Cache lazily fetches column types of tables and keeps them until they're refreshed.
+// It's safe for concurrent use.
+type Cache struct {
+	querier Querier
+
+	mu          sync.Mutex
+	columnTypes map[string]map[string]string
+	// unknown holds the columns of every table that were confirmed to be missing by a refresh,
+	// and the time they were last confirmed. It's cleared by every refresh of the table.
+	unknown map[string]map[string]time.Time
+	// now returns the current time, it's replaced in tests.
+	now func() time.Time
+}
+
+// NewCache creates a new Cache that fetches column types with the querier.
+func NewCache(querier Querier) *Cache {
+	return &Cache{
+		querier:     querier,
+		columnTypes: make(map[string]map[string]string),
+		unknown:     make(map[string]map[string]time.Time),
+		now:         time.Now,
+	}
+}
+
+// Get returns the column types of the table, fetching them if they're not cached yet.
+func (c *Cache) Get(ctx context.Context, tableName string) (map[string]string, error) {
+	c.mu.Lock()
+	columnTypes, ok := c.columnTypes[tableName]
+	c.mu.Unlock()
+
+	if ok {
+		return columnTypes, nil
+	}
+
+	return c.Refresh(ctx, tableName)
+}
+
+// Refresh fetches the column types of the table and replaces the cached ones.
+// Columns confirmed to be missing are forgotten, so that they're confirmed against the fresh column types.
+func (c *Cache) Refresh(ctx context.Context, tableName string) (map[string]string, error) {
+	columnTypes, err := GetColumnTypes(ctx, c.querier, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.columnTypes[tableName] = columnTypes
+	delete(c.unknown, tableName)
+	c.mu.Unlock()
+
 	return columnTypes, nil
 }
 
+// UnknownColumns returns the columns that don't exist in the table, in the order they're given,
+// and reports whether any of them is confirmed to be missing for the first time,
+// so that callers can report the unknown columns of a table once.
+//
+// The first time a column is found to be missing, and once the confirmation is older than
+// unknownColumnRecheckInterval, the column types of the table are refreshed to make sure it wasn't added
+// after they were cached. Columns of a table without any columns,
+// e.g. a table that doesn't exist, are never reported as unknown.
+func (c *Cache) UnknownColumns(
+	ctx context.Context, tableName string, columns []string,
+) (unknown []string, first bool, err error) {
+	columnTypes, err := c.Get(ctx, tableName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	unknown = missingColumns(columnTypes, columns)
+	if len(unknown) == 0 {
+		return nil, false, nil
+	}
+
+	c.mu.Lock()
+	now := c.now()
+	confirmed := true
+	// seen holds the columns confirmed before, even if a while ago, they're not reported for the first time again
+	seen := make(map[string]bool, len(unknown))
+	for _, column := range unknown {
+		confirmedAt, ok := c.unknown[tableName][column]
+		confirmed = confirmed && ok && now.Sub(confirmedAt) < unknownColumnRecheckInterval
+		seen[column] = ok
+	}
+	c.mu.Unlock()
+
+	if confirmed {
+		return unknown, false, nil
+	}
+
+	columnTypes, err = c.Refresh(ctx, tableName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	unknown = missingColumns(columnTypes, columns)
+
+	c.mu.Lock()
+	if c.unknown[tableName] == nil {
+		c.unknown[tableName] = make(map[string]time.Time)
+	}
+	for _, column := range unknown {
+		// another caller may have confirmed the column in the meantime
+		_, ok := c.unknown[tableName][column]
+		first = first || (!ok && !seen[column])
+		c.unknown[tableName][column] = now
+	}
+	c.mu.Unlock()
+
+	return unknown, first, nil
+}
+
+// missingColumns returns the columns that have no column types.
+func missingColumns(columnTypes map[string]string, columns []string) []string {
+	if len(columnTypes) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, column := range columns {
+		if _, ok := columnTypes[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+
+	return missing
+}
+
 // parseTime parses a value trying to extract a time.Time from it and
 // formats the resulting value according to the TIME layout.
 func parseTime(value any) (string, error) {
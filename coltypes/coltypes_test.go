@@ -16,10 +16,14 @@ package coltypes
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/jackc/pgx/v4"
 )
 
 func TestConvertStructureData(t *testing.T) {
@@ -85,6 +89,389 @@ func TestConvertStructureData(t *testing.T) {
 			want:    opencdc.StructuredData{},
 			wantErr: true,
 		},
+		{
+			name: "success_date",
+			args: args{
+				columnTypes: map[string]string{
+					"from_string": "date",
+					"from_time":   "date",
+					"from_days":   "date",
+				},
+				data: opencdc.StructuredData{
+					"from_string": "2022-09-27T10:34:54Z",
+					"from_time":   time.Date(2022, 9, 27, 0, 0, 0, 0, time.UTC),
+					"from_days":   float64(19262),
+				},
+			},
+			want: opencdc.StructuredData{
+				"from_string": "2022-09-27",
+				"from_time":   "2022-09-27",
+				"from_days":   "2022-09-27",
+			},
+		},
+		{
+			name: "success_timestamps",
+			args: args{
+				columnTypes: map[string]string{
+					"created_at": "timestamp without time zone",
+					"updated_at": "timestamp with time zone",
+					"deleted_at": "timestamptz",
+				},
+				data: opencdc.StructuredData{
+					"created_at": "2022-09-27T12:34:54+02:00",
+					"updated_at": "2022-09-27 10:34:54",
+					"deleted_at": float64(1664274894000),
+				},
+			},
+			want: opencdc.StructuredData{
+				"created_at": time.Date(2022, 9, 27, 10, 34, 54, 0, time.UTC),
+				"updated_at": time.Date(2022, 9, 27, 10, 34, 54, 0, time.UTC),
+				"deleted_at": time.Date(2022, 9, 27, 10, 34, 54, 0, time.UTC),
+			},
+		},
+		{
+			name: "success_interval_numeric_uuid",
+			args: args{
+				columnTypes: map[string]string{
+					"duration": "interval",
+					"elapsed":  "interval",
+					"price":    "numeric",
+					"amount":   "numeric",
+					"uuid":     "uuid",
+				},
+				data: opencdc.StructuredData{
+					"duration": "1 day",
+					"elapsed":  1500 * time.Millisecond,
+					"price":    "12345678901234567890.123456789",
+					"amount":   0.1,
+					"uuid":     "{A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11}",
+				},
+			},
+			want: opencdc.StructuredData{
+				"duration": "1 day",
+				"elapsed":  "1500000 microseconds",
+				"price":    "12345678901234567890.123456789",
+				"amount":   "0.1",
+				"uuid":     "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+			},
+		},
+		{
+			name: "success_bytea_boolean_jsonb",
+			args: args{
+				columnTypes: map[string]string{
+					"from_string": "bytea",
+					"from_hex":    "bytea",
+					"from_bytes":  "bytea",
+					"active":      "boolean",
+					"deleted":     "boolean",
+					"skills":      "jsonb",
+					"tags":        "jsonb",
+				},
+				data: opencdc.StructuredData{
+					"from_string": "abcd",
+					"from_hex":    "\\xdead",
+					"from_bytes":  []byte{0xde, 0xad},
+					"active":      "yes",
+					"deleted":     float64(0),
+					"skills":      `{"read":2}`,
+					"tags":        []any{"a", "b"},
+				},
+			},
+			want: opencdc.StructuredData{
+				"from_string": "\\x61626364",
+				"from_hex":    "\\xdead",
+				"from_bytes":  "\\xdead",
+				"active":      true,
+				"deleted":     false,
+				"skills":      JSON(`{"read":2}`),
+				"tags":        JSON(`["a","b"]`),
+			},
+		},
+		{
+			name: "success_nested_by_column_type",
+			args: args{
+				columnTypes: map[string]string{
+					"document":    "jsonb",
+					"description": "text",
+					"notes":       "character varying",
+					"tags":        "text list",
+					"name":        "jsonb",
+					"count":       "jsonb",
+					"flag":        "jsonb",
+					"nothing":     "jsonb",
+					"padded":      "jsonb",
+					"broken":      "jsonb",
+				},
+				data: opencdc.StructuredData{
+					"document":    map[string]any{"tags": []any{"a"}, "rank": json.Number("1.50")},
+					"description": map[string]any{"tags": []any{"a"}},
+					"notes":       []any{"a", json.Number("1")},
+					"tags":        []any{"a", "b"},
+					"name":        "alien",
+					"count":       "123",
+					"flag":        "true",
+					"nothing":     "null",
+					"padded":      ` [1, 2]`,
+					"broken":      `{"a":`,
+					"untyped":     map[string]any{"a": true},
+				},
+			},
+			want: opencdc.StructuredData{
+				"document":    JSON(`{"rank":1.50,"tags":["a"]}`),
+				"description": `{"tags":["a"]}`,
+				"notes":       `["a",1]`,
+				"tags":        `{"a","b"}`,
+				"name":        JSON(`"alien"`),
+				"count":       JSON(`"123"`),
+				"flag":        JSON(`"true"`),
+				"nothing":     JSON(`"null"`),
+				"padded":      JSON(` [1, 2]`),
+				"broken":      JSON(`"{\"a\":"`),
+				"untyped":     `{"a":true}`,
+			},
+		},
+		{
+			name: "fail_nested_scalar_column",
+			args: args{
+				columnTypes: map[string]string{
+					"age": "integer",
+				},
+				data: opencdc.StructuredData{
+					"age": map[string]any{"years": 42},
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "fail_array_scalar_column",
+			args: args{
+				columnTypes: map[string]string{
+					"born_at": "time",
+				},
+				data: opencdc.StructuredData{
+					"born_at": []any{"10:00"},
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "success_numbers",
+			args: args{
+				columnTypes: map[string]string{
+					"small":    "smallint",
+					"big":      "bigint",
+					"native":   "integer",
+					"unsigned": "uint8",
+					"real":     "real",
+					"double":   "double precision",
+				},
+				data: opencdc.StructuredData{
+					"small":    float64(12),
+					"big":      "9007199254740993",
+					"native":   int32(7),
+					"unsigned": "18446744073709551615",
+					"real":     "1.5",
+					"double":   2,
+				},
+			},
+			want: opencdc.StructuredData{
+				"small":    int64(12),
+				"big":      int64(9007199254740993),
+				"native":   int32(7),
+				"unsigned": "18446744073709551615",
+				"real":     1.5,
+				"double":   float64(2),
+			},
+		},
+		{
+			name: "success_json_numbers",
+			args: args{
+				columnTypes: map[string]string{
+					"id":        "bigint",
+					"exponent":  "integer",
+					"unsigned":  "uint8",
+					"price":     "numeric",
+					"rating":    "double precision",
+					"born_on":   "date",
+					"logged_at": "timestamptz",
+					"active":    "boolean",
+					"untyped":   "text",
+				},
+				data: opencdc.StructuredData{
+					"id":        json.Number("9007199254740993"),
+					"exponent":  json.Number("1.2e3"),
+					"unsigned":  json.Number("18446744073709551615"),
+					"price":     json.Number("12345678901234567890.123456789"),
+					"rating":    json.Number("4.5"),
+					"born_on":   json.Number("19262"),
+					"logged_at": json.Number("1664274894000"),
+					"active":    json.Number("1"),
+					"untyped":   json.Number("12.50"),
+				},
+			},
+			want: opencdc.StructuredData{
+				"id":        int64(9007199254740993),
+				"exponent":  int64(1200),
+				"unsigned":  "18446744073709551615",
+				"price":     "12345678901234567890.123456789",
+				"rating":    4.5,
+				"born_on":   "2022-09-27",
+				"logged_at": time.Date(2022, 9, 27, 10, 34, 54, 0, time.UTC),
+				"active":    true,
+				"untyped":   json.Number("12.50"),
+			},
+		},
+		{
+			name: "fail_json_number_out_of_range",
+			args: args{
+				columnTypes: map[string]string{
+					"id": "bigint",
+				},
+				data: opencdc.StructuredData{
+					"id": json.Number("9223372036854775808"),
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "fail_smallint_out_of_range",
+			args: args{
+				columnTypes: map[string]string{
+					"small": "smallint",
+				},
+				data: opencdc.StructuredData{
+					"small": 32768,
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "fail_negative_unsigned",
+			args: args{
+				columnTypes: map[string]string{
+					"unsigned": "uint4",
+				},
+				data: opencdc.StructuredData{
+					"unsigned": json.Number("-1"),
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "success_integer_bounds",
+			args: args{
+				columnTypes: map[string]string{
+					"small_min": "smallint",
+					"int_max":   "integer",
+					"uint2_max": "uint2",
+					"uint4_max": "uint4",
+				},
+				data: opencdc.StructuredData{
+					"small_min": json.Number("-32768"),
+					"int_max":   "2147483647",
+					"uint2_max": uint16(65535),
+					"uint4_max": float64(4294967295),
+				},
+			},
+			want: opencdc.StructuredData{
+				"small_min": int64(-32768),
+				"int_max":   int64(2147483647),
+				"uint2_max": int64(65535),
+				"uint4_max": int64(4294967295),
+			},
+		},
+		{
+			name: "success_collections",
+			args: args{
+				columnTypes: map[string]string{
+					"tags":      "text[]",
+					"matrix":    "array",
+					"scores":    "integer list",
+					"nested":    "integer list list",
+					"json_list": "text list",
+					"literal":   "text list",
+					"labels":    "map[text=>text]",
+					"settings":  "map[text=>map[text=>integer]]",
+				},
+				data: opencdc.StructuredData{
+					"tags":      []any{"a", `say "hi"`, nil},
+					"matrix":    []any{[]any{json.Number("1"), json.Number("2")}, []any{json.Number("3"), json.Number("4")}},
+					"scores":    []any{1, 2.5, true},
+					"nested":    []any{[]any{json.Number("1")}, []any{}},
+					"json_list": `["a", "b"]`,
+					"literal":   `{a,b}`,
+					"labels":    map[string]any{"b": "2", "a": `c\d`},
+					"settings":  `{"ui": {"zoom": 2}}`,
+				},
+			},
+			want: opencdc.StructuredData{
+				"tags":      `{"a","say \"hi\"",NULL}`,
+				"matrix":    `{{1,2},{3,4}}`,
+				"scores":    `{1,2.5,true}`,
+				"nested":    `{{1},{}}`,
+				"json_list": `{"a","b"}`,
+				"literal":   `{a,b}`,
+				"labels":    `{"a"=>"c\\d","b"=>"2"}`,
+				"settings":  `{"ui"=>{"zoom"=>2}}`,
+			},
+		},
+		{
+			name: "fail_collection_type_mismatch",
+			args: args{
+				columnTypes: map[string]string{
+					"tags": "text list",
+				},
+				data: opencdc.StructuredData{
+					"tags": json.Number("1"),
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "fail_fractional_integer",
+			args: args{
+				columnTypes: map[string]string{
+					"id": "integer",
+				},
+				data: opencdc.StructuredData{
+					"id": 1.5,
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "fail_invalid_uuid",
+			args: args{
+				columnTypes: map[string]string{
+					"uuid": "uuid",
+				},
+				data: opencdc.StructuredData{
+					"uuid": "not-a-uuid",
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
+		{
+			name: "fail_invalid_numeric",
+			args: args{
+				columnTypes: map[string]string{
+					"price": "numeric",
+				},
+				data: opencdc.StructuredData{
+					"price": "12,5",
+				},
+			},
+			want:    opencdc.StructuredData{},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -106,3 +493,274 @@ func TestConvertStructureData(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertStructureData_ConversionError(t *testing.T) {
+	t.Parallel()
+
+	_, err := ConvertStructureData(context.Background(), map[string]string{
+		"active": "boolean",
+	}, opencdc.StructuredData{
+		"active": "maybe",
+	})
+
+	var conversionErr *ConversionError
+	if !errors.As(err, &conversionErr) {
+		t.Fatalf("ConvertStructureData() error = %v, want *ConversionError", err)
+	}
+
+	if conversionErr.Field != "active" || conversionErr.Type != "boolean" {
+		t.Errorf("ConvertStructureData() error names field %q and type %q, want %q and %q",
+			conversionErr.Field, conversionErr.Type, "active", "boolean")
+	}
+}
+
+func TestConvertStructureData_RangeError(t *testing.T) {
+	t.Parallel()
+
+	_, err := ConvertStructureData(context.Background(), map[string]string{
+		"count": "uint2",
+	}, opencdc.StructuredData{
+		"count": int64(65536),
+	})
+
+	var rangeErr *RangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("ConvertStructureData() error = %v, want *RangeError", err)
+	}
+
+	want := &RangeError{Value: "65536", Min: "0", Max: "65535"}
+	if *rangeErr != *want {
+		t.Errorf("ConvertStructureData() error = %+v, want %+v", rangeErr, want)
+	}
+
+	var conversionErr *ConversionError
+	if !errors.As(err, &conversionErr) || conversionErr.Field != "count" {
+		t.Errorf("ConvertStructureData() error = %v, want *ConversionError of field %q", err, "count")
+	}
+}
+
+// failingRows are rows that return a single column and then fail, the way pgx reports errors
+// of a query interrupted while its rows are read. The embedded pgx.Rows is nil,
+// only the methods used to read column types are implemented.
+type failingRows struct {
+	pgx.Rows
+
+	scanErr error
+	err     error
+	read    bool
+	closed  bool
+}
+
+func (r *failingRows) Next() bool {
+	if r.read {
+		return false
+	}
+
+	r.read = true
+
+	return true
+}
+
+func (r *failingRows) Scan(dest ...any) error {
+	if r.scanErr != nil {
+		return r.scanErr
+	}
+
+	*dest[0].(*string), *dest[1].(*string) = "id", "integer"
+
+	return nil
+}
+
+func (r *failingRows) Err() error {
+	return r.err
+}
+
+func (r *failingRows) Close() {
+	r.closed = true
+}
+
+// rowsQuerier is a Querier that returns the rows.
+type rowsQuerier struct {
+	rows pgx.Rows
+}
+
+func (q rowsQuerier) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return q.rows, nil
+}
+
+func TestGetColumnTypes_RowsError(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		rows *failingRows
+	}{
+		{name: "query error", rows: &failingRows{err: wantErr}},
+		{name: "scan error", rows: &failingRows{scanErr: wantErr}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cache := NewCache(rowsQuerier{rows: tt.rows})
+
+			columnTypes, err := cache.Refresh(context.Background(), "users")
+			if !errors.Is(err, wantErr) {
+				t.Errorf("Cache.Refresh() error = %v, want %v", err, wantErr)
+			}
+
+			if columnTypes != nil {
+				t.Errorf("Cache.Refresh() = %v, want no column types", columnTypes)
+			}
+
+			if !tt.rows.closed {
+				t.Error("GetColumnTypes() didn't close the rows")
+			}
+		})
+	}
+}
+
+// columnRows are rows of a table with the columns, all of them of the text data type.
+// The embedded pgx.Rows is nil, only the methods used to read column types are implemented.
+type columnRows struct {
+	pgx.Rows
+
+	columns []string
+}
+
+func (r *columnRows) Next() bool {
+	return len(r.columns) > 0
+}
+
+func (r *columnRows) Scan(dest ...any) error {
+	*dest[0].(*string), *dest[1].(*string) = r.columns[0], "text"
+	r.columns = r.columns[1:]
+
+	return nil
+}
+
+func (r *columnRows) Err() error {
+	return nil
+}
+
+func (r *columnRows) Close() {}
+
+// columnsQuerier is a Querier of a table with the columns, it counts the queries.
+type columnsQuerier struct {
+	columns []string
+	queries int
+}
+
+func (q *columnsQuerier) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	q.queries++
+
+	return &columnRows{columns: q.columns}, nil
+}
+
+func TestCache_UnknownColumns(t *testing.T) {
+	t.Parallel()
+
+	querier := &columnsQuerier{columns: []string{"id", "name"}}
+	cache := NewCache(querier)
+	ctx := context.Background()
+
+	steps := []struct {
+		columns     []string
+		wantUnknown []string
+		wantFirst   bool
+		wantQueries int
+	}{
+		// the first time the columns are missing the column types are refreshed to confirm it
+		{columns: []string{"id", "extra"}, wantUnknown: []string{"extra"}, wantFirst: true, wantQueries: 2},
+		{columns: []string{"id", "extra"}, wantUnknown: []string{"extra"}, wantFirst: false, wantQueries: 2},
+		{columns: []string{"extra", "other"}, wantUnknown: []string{"extra", "other"}, wantFirst: true, wantQueries: 3},
+		{columns: []string{"other"}, wantUnknown: []string{"other"}, wantFirst: false, wantQueries: 3},
+		{columns: []string{"id", "name"}, wantUnknown: nil, wantFirst: false, wantQueries: 3},
+	}
+
+	for i, step := range steps {
+		unknown, first, err := cache.UnknownColumns(ctx, "users", step.columns)
+		if err != nil {
+			t.Fatalf("step %d: UnknownColumns() error = %v", i, err)
+		}
+
+		if !reflect.DeepEqual(unknown, step.wantUnknown) {
+			t.Errorf("step %d: UnknownColumns() unknown = %v, want %v", i, unknown, step.wantUnknown)
+		}
+
+		if first != step.wantFirst {
+			t.Errorf("step %d: UnknownColumns() first = %v, want %v", i, first, step.wantFirst)
+		}
+
+		if querier.queries != step.wantQueries {
+			t.Errorf("step %d: queries = %d, want %d", i, querier.queries, step.wantQueries)
+		}
+	}
+}
+
+func TestCache_UnknownColumns_AddedLater(t *testing.T) {
+	t.Parallel()
+
+	querier := &columnsQuerier{columns: []string{"id"}}
+	cache := NewCache(querier)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	unknown, first, err := cache.UnknownColumns(ctx, "users", []string{"id", "extra"})
+	if err != nil || !reflect.DeepEqual(unknown, []string{"extra"}) || !first {
+		t.Fatalf("UnknownColumns() = %v, %v, %v, want [extra], true, nil", unknown, first, err)
+	}
+
+	// the column is added by an operator, the confirmation is trusted until it's old enough
+	querier.columns = []string{"id", "extra"}
+
+	now = now.Add(unknownColumnRecheckInterval / 2)
+	if unknown, _, _ := cache.UnknownColumns(ctx, "users", []string{"id", "extra"}); len(unknown) != 1 {
+		t.Fatalf("UnknownColumns() unknown = %v, want the column confirmed recently", unknown)
+	}
+
+	now = now.Add(unknownColumnRecheckInterval)
+	unknown, first, err = cache.UnknownColumns(ctx, "users", []string{"id", "extra"})
+	if err != nil || unknown != nil || first {
+		t.Errorf("UnknownColumns() = %v, %v, %v, want nil, false, nil", unknown, first, err)
+	}
+}
+
+func TestCache_Refresh_ForgetsUnknownColumns(t *testing.T) {
+	t.Parallel()
+
+	querier := &columnsQuerier{columns: []string{"id"}}
+	cache := NewCache(querier)
+	ctx := context.Background()
+
+	if _, _, err := cache.UnknownColumns(ctx, "users", []string{"extra"}); err != nil {
+		t.Fatalf("UnknownColumns() error = %v", err)
+	}
+
+	querier.columns = []string{"id", "extra"}
+
+	if _, err := cache.Refresh(ctx, "users"); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// the column is dropped once again, so it's confirmed to be missing anew
+	querier.columns = []string{"id"}
+	queries := querier.queries
+
+	if _, err := cache.Refresh(ctx, "users"); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	unknown, first, err := cache.UnknownColumns(ctx, "users", []string{"extra"})
+	if err != nil || !reflect.DeepEqual(unknown, []string{"extra"}) || !first {
+		t.Errorf("UnknownColumns() = %v, %v, %v, want [extra], true, nil", unknown, first, err)
+	}
+
+	if querier.queries != queries+2 {
+		t.Errorf("queries = %d, want %d, the missing column must be confirmed by a refresh", querier.queries, queries+2)
+	}
+}
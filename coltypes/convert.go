@@ -0,0 +1,364 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dateDataTypeLayout is a time format for the DATE data type.
+	dateDataTypeLayout = "2006-01-02"
+	// byteaHexPrefix is the prefix of the hex format of the BYTEA data type.
+	byteaHexPrefix = `\x`
+)
+
+// timestampLayouts are the layouts of strings accepted by the TIMESTAMP and TIMESTAMPTZ data types,
+// strings without a time zone are considered to be in UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	dateDataTypeLayout,
+}
+
+var (
+	errUnsupportedValue = errors.New("unsupported value")
+	errNotInteger       = errors.New("value is not an integer")
+)
+
+// converter converts a value to a value accepted by a column of a certain data type.
+type converter func(value any) (any, error)
+
+// converters maps data type names to their converters.
+// Data types are listed both with their SQL names reported by the information_schema and their aliases.
+var converters = map[string]converter{
+	timeDataType:                  func(value any) (any, error) { return parseTime(value) },
+	"date":                        parseDate,
+	"timestamp":                   parseTimestamp,
+	"timestamp without time zone": parseTimestamp,
+	"timestamptz":                 parseTimestampTZ,
+	"timestamp with time zone":    parseTimestampTZ,
+	"interval":                    parseInterval,
+	"numeric":                     parseNumeric,
+	"decimal":                     parseNumeric,
+	"uuid":                        parseUUID,
+	"bytea":                       parseBytea,
+	"boolean":                     parseBoolean,
+	"bool":                        parseBoolean,
+	"smallint":                    signedInt(16),
+	"int2":                        signedInt(16),
+	"integer":                     signedInt(32),
+	"int":                         signedInt(32),
+	"int4":                        signedInt(32),
+	"bigint":                      signedInt(64),
+	"int8":                        signedInt(64),
+	"uint2":                       unsignedInt(16),
+	"uint4":                       unsignedInt(32),
+	"uint8":                       unsignedInt(64),
+	"real":                        parseFloat,
+	"float4":                      parseFloat,
+	"double precision":            parseFloat,
+	"double":                      parseFloat,
+	"float8":                      parseFloat,
+	jsonbDataType:                 parseJSON,
+	"json":                        parseJSON,
+	textDataType:                  parseText,
+	"character varying":           parseText,
+	"varchar":                     parseText,
+	"character":                   parseText,
+	"char":                        parseText,
+	"bpchar":                      parseText,
+}
+
+// parseDate parses a date from an ISO 8601 string, a time.Time,
+// or a number of days since the Unix epoch, and formats it according to the DATE layout.
+func parseDate(value any) (any, error) {
+	if days, ok := toFloat(value); ok {
+		if days != math.Trunc(days) {
+			return nil, errNotInteger
+		}
+
+		return time.Unix(0, 0).UTC().AddDate(0, 0, int(days)).Format(dateDataTypeLayout), nil
+	}
+
+	t, err := toTime(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Format(dateDataTypeLayout), nil
+}
+
+// parseTimestamp parses a timestamp from a string, a time.Time, or a number of milliseconds since the Unix epoch.
+// The timestamp is converted to UTC, since the TIMESTAMP data type has no time zone.
+func parseTimestamp(value any) (any, error) {
+	t, err := toTime(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.UTC(), nil
+}
+
+// parseTimestampTZ parses a timestamp from a string, a time.Time, or a number of milliseconds since the Unix epoch.
+func parseTimestampTZ(value any) (any, error) {
+	return toTime(value)
+}
+
+// parseInterval parses an interval from a string, which is passed to the database as is, or a time.Duration.
+func parseInterval(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case time.Duration:
+		return strconv.FormatInt(v.Microseconds(), 10) + " microseconds", nil
+	default:
+		return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+	}
+}
+
+// parseNumeric parses a number, or a string representing a number, into a string that keeps its exact value.
+func parseNumeric(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if _, ok := new(big.Rat).SetString(v); !ok {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+
+		return v, nil
+	case json.Number:
+		if _, ok := new(big.Rat).SetString(v.String()); !ok {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+
+		return v.String(), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+
+	if i, ok := toInt64(value); ok {
+		return strconv.FormatInt(i, 10), nil
+	}
+
+	if u, ok := value.(uint64); ok {
+		return strconv.FormatUint(u, 10), nil
+	}
+
+	return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+}
+
+// parseUUID parses a UUID from a string, with or without hyphens and braces,
+// or a 16-byte array, and formats it in the canonical form.
+func parseUUID(value any) (any, error) {
+	var raw []byte
+
+	switch v := value.(type) {
+	case string:
+		decoded, err := hex.DecodeString(strings.NewReplacer("-", "", "{", "", "}", "").Replace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid uuid %q: %w", v, err)
+		}
+
+		raw = decoded
+	case [16]byte:
+		raw = v[:]
+	case []byte:
+		raw = v
+	default:
+		return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+	}
+
+	if len(raw) != 16 {
+		return nil, fmt.Errorf("invalid uuid length %d", len(raw))
+	}
+
+	encoded := hex.EncodeToString(raw)
+
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:], nil
+}
+
+// parseBytea parses bytes from a []byte, a string in the hex format, or any other string, which is taken
+// as its UTF-8 bytes, and formats them in the hex format of the BYTEA data type.
+// Strings aren't decoded from base64, since plain text may happen to be valid base64,
+// bytes encoded as base64 are decoded only if the schema says so, see ConvertSchemaData.
+func parseBytea(value any) (any, error) {
+	switch v := value.(type) {
+	case []byte:
+		return byteaHexPrefix + hex.EncodeToString(v), nil
+	case string:
+		if strings.HasPrefix(v, byteaHexPrefix) {
+			if _, err := hex.DecodeString(v[len(byteaHexPrefix):]); err != nil {
+				return nil, fmt.Errorf("invalid hex: %w", err)
+			}
+
+			return v, nil
+		}
+
+		return byteaHexPrefix + hex.EncodeToString([]byte(v)), nil
+	default:
+		return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+	}
+}
+
+// parseBoolean parses a boolean from a bool, a number that is either 0 or 1,
+// or a string accepted by the BOOLEAN data type.
+func parseBoolean(value any) (any, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		default:
+			return nil, fmt.Errorf("invalid boolean %q", v)
+		}
+	}
+
+	if f, ok := toFloat(value); ok {
+		switch f {
+		case 0:
+			return false, nil
+		case 1:
+			return true, nil
+		default:
+			return nil, fmt.Errorf("invalid boolean %v", f)
+		}
+	}
+
+	return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+}
+
+// parseFloat parses a floating-point number from a number or a string.
+func parseFloat(value any) (any, error) {
+	if s, ok := value.(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse float: %w", err)
+		}
+
+		return f, nil
+	}
+
+	if f, ok := toFloat(value); ok {
+		return f, nil
+	}
+
+	return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+}
+
+// toTime converts a string in one of the timestampLayouts, a time.Time,
+// or a number of milliseconds since the Unix epoch into a time.Time.
+func toTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+	}
+
+	if millis, ok := toInt64(value); ok {
+		return time.UnixMilli(millis).UTC(), nil
+	}
+
+	if number, ok := value.(json.Number); ok {
+		if millis, err := number.Int64(); err == nil {
+			return time.UnixMilli(millis).UTC(), nil
+		}
+	}
+
+	if millis, ok := toFloat(value); ok {
+		return time.UnixMicro(int64(millis * 1000)).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w %T", errUnsupportedValue, value)
+}
+
+// toInt64 converts a value of a signed or unsigned integer type into an int64.
+// It reports false for other types and unsigned values that overflow an int64.
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return 0, false
+		}
+
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat converts a value of a numeric type, or a json.Number, into a float64.
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+
+		return f, err == nil
+	}
+
+	if i, ok := toInt64(value); ok {
+		return float64(i), true
+	}
+
+	return 0, false
+}
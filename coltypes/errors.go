@@ -0,0 +1,52 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import "fmt"
+
+// ConversionError occurs when a value can't be converted to the type of its column.
+type ConversionError struct {
+	// Field is the name of the field the value belongs to.
+	Field string
+	// Type is the data type of the column.
+	Type string
+	// Err is the reason of the failure.
+	Err error
+}
+
+// Error returns the error message naming the field and the target type.
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("convert field %q to %s: %s", e.Field, e.Type, e.Err)
+}
+
+// Unwrap returns the reason of the failure.
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// RangeError occurs when an integer is out of the range of its column's data type.
+type RangeError struct {
+	// Value is the integer that is out of the range.
+	Value string
+	// Min is the minimum value of the data type.
+	Min string
+	// Max is the maximum value of the data type.
+	Max string
+}
+
+// Error returns the error message with the value and the range.
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("value %s is out of range [%s, %s]", e.Value, e.Min, e.Max)
+}
@@ -0,0 +1,54 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// Data types of columns inferred from values.
+	textDataType        = "text"
+	booleanDataType     = "boolean"
+	bigintDataType      = "bigint"
+	numericDataType     = "numeric"
+	timestamptzDataType = "timestamptz"
+	byteaDataType       = "bytea"
+	jsonbDataType       = "jsonb"
+)
+
+// InferType returns the data type of a column that can hold the value.
+// Values of unknown types, including nil, are stored in text columns.
+func InferType(value any) string {
+	switch value.(type) {
+	case bool:
+		return booleanDataType
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return bigintDataType
+	case uint, uint64, float32, float64, json.Number:
+		// numbers decoded from JSON may be either integers or decimals,
+		// so they're stored in numeric columns that hold both of them exactly
+		return numericDataType
+	case time.Time:
+		return timestamptzDataType
+	case []byte:
+		return byteaDataType
+	case map[string]any, []any, JSON:
+		return jsonbDataType
+	default:
+		return textDataType
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInferType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "nil", value: nil, want: "text"},
+		{name: "string", value: "Anon", want: "text"},
+		{name: "bool", value: true, want: "boolean"},
+		{name: "int", value: 1, want: "bigint"},
+		{name: "float", value: 1.5, want: "numeric"},
+		{name: "time", value: time.Now(), want: "timestamptz"},
+		{name: "bytes", value: []byte{0xde, 0xad}, want: "bytea"},
+		{name: "object", value: map[string]any{"read": 2}, want: "jsonb"},
+		{name: "array", value: []any{"a"}, want: "jsonb"},
+		{name: "document", value: JSON(`{"read":2}`), want: "jsonb"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := InferType(tt.value); got != tt.want {
+				t.Errorf("InferType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// signedInt returns a converter of signed integers of the bit size that checks their range.
+// Values of integer types within the range are kept as is, other values are converted to an int64.
+func signedInt(bitSize uint) converter {
+	limit := new(big.Int).Lsh(big.NewInt(1), bitSize-1)
+	minValue := new(big.Int).Neg(limit)
+	maxValue := new(big.Int).Sub(limit, big.NewInt(1))
+
+	return func(value any) (any, error) {
+		i, err := toBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if i.Cmp(minValue) < 0 || i.Cmp(maxValue) > 0 {
+			return nil, &RangeError{Value: i.String(), Min: minValue.String(), Max: maxValue.String()}
+		}
+
+		if _, ok := toInt64(value); ok {
+			return value, nil
+		}
+
+		return i.Int64(), nil
+	}
+}
+
+// unsignedInt returns a converter of unsigned integers of the bit size that checks their range.
+// Values are converted to an int64, or formatted as a string if they don't fit into it,
+// since they can't be passed to the database as signed integers.
+func unsignedInt(bitSize uint) converter {
+	maxValue := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bitSize), big.NewInt(1))
+
+	return func(value any) (any, error) {
+		i, err := toBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if i.Sign() < 0 || i.Cmp(maxValue) > 0 {
+			return nil, &RangeError{Value: i.String(), Min: "0", Max: maxValue.String()}
+		}
+
+		if !i.IsInt64() {
+			return i.String(), nil
+		}
+
+		return i.Int64(), nil
+	}
+}
+
+// toBigInt converts a value of an integer type, a number without a fractional part,
+// or a string representing an integer into a big.Int.
+func toBigInt(value any) (*big.Int, error) {
+	switch v := value.(type) {
+	case string:
+		i, ok := new(big.Int).SetString(strings.TrimSpace(v), 10)
+		if !ok {
+			return nil, fmt.Errorf("parse integer %q: %w", v, strconv.ErrSyntax)
+		}
+
+		return i, nil
+	case json.Number:
+		rat, ok := new(big.Rat).SetString(v.String())
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+
+		if !rat.IsInt() {
+			return nil, errNotInteger
+		}
+
+		return rat.Num(), nil
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	}
+
+	if i, ok := toInt64(value); ok {
+		return big.NewInt(i), nil
+	}
+
+	if f, ok := toFloat(value); ok {
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return nil, errNotInteger
+		}
+
+		i, _ := big.NewFloat(f).Int(nil)
+
+		return i, nil
+	}
+
+	return nil, fmt.Errorf("%w %T", errUnsupportedValue, value)
+}
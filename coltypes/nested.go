@@ -0,0 +1,138 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNestedValue occurs when a nested object or array is written to a column of a scalar data type.
+var ErrNestedValue = errors.New(
+	"nested objects and arrays can only be written to jsonb, text, list, map and array columns")
+
+// JSON is a JSON document written to a jsonb column.
+// It's sent to the database as the text of the document, which Materialize parses into a jsonb value,
+// as opposed to a string that is written to a jsonb column as a JSON string.
+type JSON string
+
+// Value returns the text of the document, so that it's written as a literal of the document.
+func (j JSON) Value() (driver.Value, error) {
+	return string(j), nil
+}
+
+// String returns the text of the document.
+func (j JSON) String() string {
+	return string(j)
+}
+
+// isNested reports whether the value is an object or an array decoded from JSON.
+func isNested(value any) bool {
+	switch value.(type) {
+	case map[string]any, []any:
+		return true
+	default:
+		return false
+	}
+}
+
+// lookupNestedConverter returns the converter of nested objects and arrays written to a column of the data type:
+//   - jsonb columns get the value as a JSON document,
+//   - text columns get the value encoded as JSON text,
+//   - list, map and array columns get the value encoded as a literal of the collection,
+//   - columns of other data types reject the value with the ErrNestedValue.
+//
+// Values of columns with unknown data types are encoded as JSON text.
+func lookupNestedConverter(dataType string) converter {
+	if dataType == "" {
+		return parseText
+	}
+
+	convert, ok := lookupConverter(dataType)
+	if !ok {
+		return rejectNested
+	}
+
+	switch {
+	case dataType == jsonbDataType, dataType == "json", isTextDataType(dataType),
+		isListDataType(dataType), isMapDataType(dataType):
+		return convert
+	default:
+		return rejectNested
+	}
+}
+
+// isTextDataType reports whether the data type is one of the character data types.
+func isTextDataType(dataType string) bool {
+	switch dataType {
+	case textDataType, "character varying", "varchar", "character", "char", "bpchar":
+		return true
+	default:
+		return false
+	}
+}
+
+// rejectNested is a converter of nested values written to columns of scalar data types.
+func rejectNested(value any) (any, error) {
+	return nil, fmt.Errorf("%w, got %T", ErrNestedValue, value)
+}
+
+// parseJSON encodes a value into a JSON document.
+// Strings that contain a valid JSON object or array are considered to be documents, other strings,
+// including scalar JSON such as "123", "true" or "null", become JSON strings. JSON values are kept as is.
+func parseJSON(value any) (any, error) {
+	switch v := value.(type) {
+	case JSON:
+		return v, nil
+	case string:
+		if isJSONDocument(v) {
+			return JSON(v), nil
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal json: %w", err)
+	}
+
+	return JSON(encoded), nil
+}
+
+// isJSONDocument reports whether the string contains a valid JSON object or array.
+func isJSONDocument(s string) bool {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return false
+	}
+
+	return json.Valid([]byte(trimmed))
+}
+
+// parseText encodes nested objects and arrays into JSON text. Other values are kept as is.
+func parseText(value any) (any, error) {
+	if !isNested(value) {
+		return value, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal json: %w", err)
+	}
+
+	return string(encoded), nil
+}
@@ -0,0 +1,386 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/hamba/avro/v2"
+)
+
+// maxNumericPrecision is the maximum precision of the NUMERIC data type in Materialize.
+const maxNumericPrecision = 39
+
+// timeOfDayLayout is the layout of TIME values converted from Avro times, which are precise up to microseconds.
+const timeOfDayLayout = "15:04:05.999999"
+
+// ErrNotRecordSchema occurs when a schema of a key or a payload isn't an Avro record.
+var ErrNotRecordSchema = errors.New("schema is not a record")
+
+// SchemaColumnTypes returns a map of the fields of an Avro record schema and the data types of columns
+// that can hold their values.
+func SchemaColumnTypes(schema avro.Schema) (map[string]string, error) {
+	record, ok := resolveRef(schema).(*avro.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotRecordSchema, schema.Type())
+	}
+
+	columnTypes := make(map[string]string, len(record.Fields()))
+	for _, field := range record.Fields() {
+		columnTypes[field.Name()] = SchemaType(field.Type())
+	}
+
+	return columnTypes, nil
+}
+
+// SchemaType returns the data type of a column that can hold values of the Avro schema.
+// Nullable unions are mapped to the data type of their non-null type,
+// records, arrays, maps and other unions are stored in jsonb columns.
+func SchemaType(schema avro.Schema) string {
+	schema = resolveRef(schema)
+
+	if logicalType, ok := logicalSchemaType(schema); ok {
+		return logicalType
+	}
+
+	switch schema.Type() {
+	case avro.Boolean:
+		return booleanDataType
+	case avro.Int:
+		return "integer"
+	case avro.Long:
+		return bigintDataType
+	case avro.Float:
+		return "real"
+	case avro.Double:
+		return "double precision"
+	case avro.Bytes, avro.Fixed:
+		return byteaDataType
+	case avro.Union:
+		union, _ := schema.(*avro.UnionSchema)
+		if union.Nullable() && len(union.Types()) == 2 {
+			_, typ := union.Indices()
+
+			return SchemaType(union.Types()[typ])
+		}
+
+		return jsonbDataType
+	case avro.Record, avro.Array, avro.Map:
+		return jsonbDataType
+	default:
+		// strings, enums and nulls
+		return textDataType
+	}
+}
+
+// logicalSchemaType returns the data type of a column that can hold values of the schema's logical type.
+func logicalSchemaType(schema avro.Schema) (string, bool) {
+	logicalTypeSchema, ok := schema.(avro.LogicalTypeSchema)
+	if !ok || logicalTypeSchema.Logical() == nil {
+		return "", false
+	}
+
+	switch logical := logicalTypeSchema.Logical(); logical.Type() {
+	case avro.Decimal:
+		decimal, _ := logical.(*avro.DecimalLogicalSchema)
+		if decimal.Precision() > maxNumericPrecision {
+			return numericDataType, true
+		}
+
+		return fmt.Sprintf("numeric(%d,%d)", decimal.Precision(), decimal.Scale()), true
+	case avro.UUID:
+		return "uuid", true
+	case avro.Date:
+		return "date", true
+	case avro.TimeMillis, avro.TimeMicros:
+		return timeDataType, true
+	case avro.TimestampMillis, avro.TimestampMicros:
+		return timestamptzDataType, true
+	case avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+		return "timestamp", true
+	case avro.Duration:
+		return "interval", true
+	default:
+		return "", false
+	}
+}
+
+// resolveRef returns the schema a reference refers to, or the schema itself if it's not a reference.
+func resolveRef(schema avro.Schema) avro.Schema {
+	if ref, ok := schema.(*avro.RefSchema); ok {
+		return ref.Schema()
+	}
+
+	return schema
+}
+
+// ConvertSchemaData converts the values of data decoded from JSON according to the fields of an Avro record schema,
+// so that they're accepted by ConvertStructureData and the columns they're written to:
+//   - decimals, encoded as fractions or numbers, become decimal strings with the scale of the schema,
+//   - timestamps encoded as epoch numbers become time.Time values, respecting the precision of the schema,
+//   - times of day, which the SDK decodes into durations and marshals as nanoseconds, become TIME strings,
+//   - bytes encoded as base64 strings become []byte values,
+//   - records, arrays and maps encoded as strings of valid JSON become JSON documents,
+//   - values of records, arrays, maps and nullable unions are converted recursively.
+//
+// Fields that aren't in the schema and values of other types are kept as is.
+func ConvertSchemaData(schema avro.Schema, data opencdc.StructuredData) (opencdc.StructuredData, error) {
+	record, ok := resolveRef(schema).(*avro.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotRecordSchema, schema.Type())
+	}
+
+	result := make(opencdc.StructuredData, len(data))
+	for key, value := range data {
+		result[key] = value
+	}
+
+	for _, field := range record.Fields() {
+		value, ok := result[field.Name()]
+		if !ok {
+			continue
+		}
+
+		converted, err := convertSchemaValue(field.Type(), value)
+		if err != nil {
+			return nil, fmt.Errorf("convert field %q: %w", field.Name(), err)
+		}
+
+		result[field.Name()] = converted
+	}
+
+	return result, nil
+}
+
+// convertSchemaValue converts a value decoded from JSON according to its Avro schema.
+func convertSchemaValue(schema avro.Schema, value any) (any, error) {
+	schema = resolveRef(schema)
+	if value == nil {
+		return nil, nil
+	}
+
+	if logicalTypeSchema, ok := schema.(avro.LogicalTypeSchema); ok && logicalTypeSchema.Logical() != nil {
+		return convertLogicalValue(logicalTypeSchema.Logical(), value)
+	}
+
+	switch s := schema.(type) {
+	case *avro.PrimitiveSchema:
+		if s.Type() != avro.Bytes {
+			return value, nil
+		}
+
+		return decodeBase64(value)
+
+	case *avro.FixedSchema:
+		return decodeBase64(value)
+
+	case *avro.RecordSchema:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return schemaDocument(value), nil
+		}
+
+		converted, err := ConvertSchemaData(s, object)
+		if err != nil {
+			return nil, err
+		}
+
+		// nested objects are kept as plain maps, the same way they're decoded from JSON
+		return map[string]any(converted), nil
+
+	case *avro.ArraySchema:
+		return convertSchemaArray(s, value)
+
+	case *avro.MapSchema:
+		return convertSchemaMap(s, value)
+
+	case *avro.UnionSchema:
+		if !s.Nullable() || len(s.Types()) != 2 {
+			return value, nil
+		}
+
+		_, typ := s.Indices()
+
+		return convertSchemaValue(s.Types()[typ], value)
+
+	default:
+		return value, nil
+	}
+}
+
+// convertSchemaArray converts the items of an array decoded from JSON according to the array's schema.
+func convertSchemaArray(schema *avro.ArraySchema, value any) (any, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return schemaDocument(value), nil
+	}
+
+	result := make([]any, len(items))
+	for i, item := range items {
+		converted, err := convertSchemaValue(schema.Items(), item)
+		if err != nil {
+			return nil, fmt.Errorf("convert item %d: %w", i, err)
+		}
+
+		result[i] = converted
+	}
+
+	return result, nil
+}
+
+// convertSchemaMap converts the values of a map decoded from JSON according to the map's schema.
+func convertSchemaMap(schema *avro.MapSchema, value any) (any, error) {
+	values, ok := value.(map[string]any)
+	if !ok {
+		return schemaDocument(value), nil
+	}
+
+	result := make(map[string]any, len(values))
+	for key, item := range values {
+		converted, err := convertSchemaValue(schema.Values(), item)
+		if err != nil {
+			return nil, fmt.Errorf("convert value %q: %w", key, err)
+		}
+
+		result[key] = converted
+	}
+
+	return result, nil
+}
+
+// schemaDocument returns a string of valid JSON as a JSON document, since the schema says the value is a record,
+// an array or a map, so that it's written to jsonb columns as such. Other values are kept as is.
+func schemaDocument(value any) any {
+	if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+		return JSON(s)
+	}
+
+	return value
+}
+
+// convertLogicalValue converts a value decoded from JSON according to its Avro logical type.
+func convertLogicalValue(logical avro.LogicalSchema, value any) (any, error) {
+	switch logical.Type() {
+	case avro.Decimal:
+		decimal, _ := logical.(*avro.DecimalLogicalSchema)
+
+		return convertDecimal(value, decimal.Scale())
+	case avro.TimestampMillis, avro.LocalTimestampMillis:
+		return convertEpoch(value, time.UnixMilli)
+	case avro.TimestampMicros, avro.LocalTimestampMicros:
+		return convertEpoch(value, time.UnixMicro)
+	case avro.TimeMillis, avro.TimeMicros:
+		return convertTimeOfDay(value)
+	default:
+		return value, nil
+	}
+}
+
+// convertDecimal formats a decimal encoded as a fraction, e.g. a marshaled *big.Rat, or a number
+// as a decimal string with the scale.
+func convertDecimal(value any, scale int) (any, error) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return value, nil
+	}
+
+	rat, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("parse decimal %q: %w", text, errUnsupportedValue)
+	}
+
+	return rat.FloatString(scale), nil
+}
+
+// convertEpoch converts an epoch number to a time.Time in UTC with the conversion of the schema's precision.
+// Timestamps encoded as strings are kept as is.
+func convertEpoch(value any, fromEpoch func(int64) time.Time) (any, error) {
+	switch number := value.(type) {
+	case json.Number:
+		epoch, err := number.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("convert epoch %v: %w", number, errNotInteger)
+		}
+
+		return fromEpoch(epoch).UTC(), nil
+	case float64:
+		if number != math.Trunc(number) {
+			return nil, fmt.Errorf("convert epoch %v: %w", number, errNotInteger)
+		}
+
+		return fromEpoch(int64(number)).UTC(), nil
+	default:
+		return value, nil
+	}
+}
+
+// convertTimeOfDay converts a time of day to a TIME string, e.g. 10:34:54.123.
+// Avro times are decoded into time.Duration values, which are marshaled to JSON as nanoseconds,
+// so numbers are taken as nanoseconds after midnight regardless of the schema's precision.
+// Times encoded as strings are kept as is.
+func convertTimeOfDay(value any) (any, error) {
+	var nanoseconds int64
+	switch v := value.(type) {
+	case time.Duration:
+		nanoseconds = int64(v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("convert time of day %v: %w", v, errNotInteger)
+		}
+
+		nanoseconds = n
+	case float64:
+		if v != math.Trunc(v) {
+			return nil, fmt.Errorf("convert time of day %v: %w", v, errNotInteger)
+		}
+
+		nanoseconds = int64(v)
+	default:
+		return value, nil
+	}
+
+	return time.Time{}.Add(time.Duration(nanoseconds)).Format(timeOfDayLayout), nil
+}
+
+// decodeBase64 decodes bytes encoded as a base64 string, which is how []byte values are marshaled to JSON.
+func decodeBase64(value any) (any, error) {
+	text, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,251 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/hamba/avro/v2"
+)
+
+func TestSchemaColumnTypes(t *testing.T) {
+	t.Parallel()
+
+	schema := avro.MustParse(`{
+		"type": "record",
+		"name": "user",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "age", "type": "int"},
+			{"name": "name", "type": ["null", "string"]},
+			{"name": "active", "type": "boolean"},
+			{"name": "rating", "type": "double"},
+			{"name": "avatar", "type": "bytes"},
+			{"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+			{"name": "uuid", "type": {"type": "string", "logicalType": "uuid"}},
+			{"name": "born_on", "type": {"type": "int", "logicalType": "date"}},
+			{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+			{"name": "tags", "type": {"type": "array", "items": "string"}},
+			{"name": "address", "type": {"type": "record", "name": "address", "fields": [
+				{"name": "city", "type": "string"}
+			]}}
+		]
+	}`)
+
+	got, err := SchemaColumnTypes(schema)
+	if err != nil {
+		t.Fatalf("SchemaColumnTypes() error = %v", err)
+	}
+
+	want := map[string]string{
+		"id":         "bigint",
+		"age":        "integer",
+		"name":       "text",
+		"active":     "boolean",
+		"rating":     "double precision",
+		"avatar":     "bytea",
+		"balance":    "numeric(10,2)",
+		"uuid":       "uuid",
+		"born_on":    "date",
+		"created_at": "timestamptz",
+		"tags":       "jsonb",
+		"address":    "jsonb",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaColumnTypes() = %v, want %v", got, want)
+	}
+}
+
+func TestSchemaColumnTypes_NotRecord(t *testing.T) {
+	t.Parallel()
+
+	_, err := SchemaColumnTypes(avro.MustParse(`"string"`))
+	if !errors.Is(err, ErrNotRecordSchema) {
+		t.Errorf("SchemaColumnTypes() error = %v, want %v", err, ErrNotRecordSchema)
+	}
+}
+
+func TestConvertSchemaData(t *testing.T) {
+	t.Parallel()
+
+	schema := avro.MustParse(`{
+		"type": "record",
+		"name": "order",
+		"fields": [
+			{"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 3}},
+			{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "updated_at", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}]},
+			{"name": "checksum", "type": {"type": "fixed", "name": "checksum", "size": 2}},
+			{"name": "amounts", "type": {"type": "array", "items": {
+				"type": "bytes", "logicalType": "decimal", "precision": 4, "scale": 1
+			}}},
+			{"name": "name", "type": "string"}
+		]
+	}`)
+
+	got, err := ConvertSchemaData(schema, opencdc.StructuredData{
+		"total":      1.5,
+		"created_at": float64(1664274894000),
+		"updated_at": nil,
+		"checksum":   "3q0=",
+		"amounts":    []any{"1/4", "2"},
+		"name":       "Anon",
+		"extra":      float64(1),
+	})
+	if err != nil {
+		t.Fatalf("ConvertSchemaData() error = %v", err)
+	}
+
+	want := opencdc.StructuredData{
+		"total":      "1.500",
+		"created_at": time.Date(2022, 9, 27, 10, 34, 54, 0, time.UTC),
+		"updated_at": nil,
+		"checksum":   []byte{0xde, 0xad},
+		"amounts":    []any{"0.3", "2.0"},
+		"name":       "Anon",
+		"extra":      float64(1),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertSchemaData() = %v, want %v", got, want)
+	}
+}
+
+func TestConvertSchemaData_TimeOfDay(t *testing.T) {
+	t.Parallel()
+
+	schema := avro.MustParse(`{
+		"type": "record",
+		"name": "shift",
+		"fields": [
+			{"name": "starts_at", "type": {"type": "int", "logicalType": "time-millis"}},
+			{"name": "ends_at", "type": {"type": "long", "logicalType": "time-micros"}},
+			{"name": "break_at", "type": ["null", {"type": "int", "logicalType": "time-millis"}]}
+		]
+	}`)
+
+	// the values are encoded and decoded the same way the schema extraction middleware of the SDK does it,
+	// which decodes Avro times into time.Duration values, and then marshaled to JSON and decoded by the destination
+	encoded, err := avro.Marshal(schema, map[string]any{
+		"starts_at": 10*time.Hour + 30*time.Minute + 45*time.Second + 123*time.Millisecond,
+		"ends_at":   18*time.Hour + 30*time.Minute + time.Microsecond,
+		"break_at":  12 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("avro.Marshal() error = %v", err)
+	}
+
+	var decoded map[string]any
+	if err := avro.Unmarshal(schema, encoded, &decoded); err != nil {
+		t.Fatalf("avro.Unmarshal() error = %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(opencdc.StructuredData(decoded).Bytes()))
+	decoder.UseNumber()
+
+	var data opencdc.StructuredData
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	data["closes_at"] = "20:00:00"
+
+	got, err := ConvertSchemaData(schema, data)
+	if err != nil {
+		t.Fatalf("ConvertSchemaData() error = %v", err)
+	}
+
+	want := opencdc.StructuredData{
+		"starts_at": "10:30:45.123",
+		"ends_at":   "18:30:00.000001",
+		"break_at":  "12:00:00",
+		"closes_at": "20:00:00",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertSchemaData() = %v, want %v", got, want)
+	}
+
+	// the converted values are accepted by the TIME columns
+	for key, value := range got {
+		if _, err := parseTime(value); err != nil {
+			t.Errorf("parseTime(%q) error = %v", key, err)
+		}
+	}
+}
+
+func TestConvertSchemaData_EncodedDocuments(t *testing.T) {
+	t.Parallel()
+
+	schema := avro.MustParse(`{
+		"type": "record",
+		"name": "event",
+		"fields": [
+			{"name": "scores", "type": {"type": "array", "items": "int"}},
+			{"name": "labels", "type": {"type": "map", "values": "string"}},
+			{"name": "note", "type": "string"}
+		]
+	}`)
+
+	got, err := ConvertSchemaData(schema, opencdc.StructuredData{
+		"scores": "null",
+		"labels": `{"env":"prod"}`,
+		"note":   "[1]",
+	})
+	if err != nil {
+		t.Fatalf("ConvertSchemaData() error = %v", err)
+	}
+
+	want := opencdc.StructuredData{
+		"scores": JSON("null"),
+		"labels": JSON(`{"env":"prod"}`),
+		"note":   "[1]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertSchemaData() = %v, want %v", got, want)
+	}
+
+	converted, err := ConvertStructureData(context.Background(), map[string]string{
+		"scores": "jsonb",
+		"labels": "map[text=>text]",
+	}, got)
+	if err != nil {
+		t.Fatalf("ConvertStructureData() error = %v", err)
+	}
+
+	if converted["scores"] != JSON("null") {
+		t.Errorf("ConvertStructureData() scores = %v, want the JSON document null", converted["scores"])
+	}
+
+	if converted["labels"] != `{"env"=>"prod"}` {
+		t.Errorf("ConvertStructureData() labels = %v, want a map literal", converted["labels"])
+	}
+}
+
+func TestConvertSchemaData_InvalidBytes(t *testing.T) {
+	t.Parallel()
+
+	schema := avro.MustParse(`{"type": "record", "name": "file", "fields": [{"name": "data", "type": "bytes"}]}`)
+
+	_, err := ConvertSchemaData(schema, opencdc.StructuredData{"data": "not base64!"})
+	if err == nil {
+		t.Error("ConvertSchemaData() error = nil, want an error")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// tableNameSeparator separates the database, schema and table parts of a qualified table name.
+	tableNameSeparator = "."
+
+	// maxTableNameParts is the number of parts of a fully qualified table name.
+	maxTableNameParts = 3
+
+	// maxIdentifierLength is the maximum length of an identifier in Materialize.
+	maxIdentifierLength = 63
+)
+
+var (
+	// ErrEmptyTableNamePart occurs when a part of a qualified table name is empty.
+	ErrEmptyTableNamePart = errors.New("table name contains an empty part")
+	// ErrTableNamePartTooLong occurs when a part of a table name is longer than the maximum identifier length.
+	ErrTableNamePartTooLong = errors.New("table name part is too long")
+	// ErrTooManyTableNameParts occurs when a table name has more parts than database.schema.table.
+	ErrTooManyTableNameParts = errors.New("table name must be table, schema.table or database.schema.table")
+)
+
+// TableName is a table name optionally qualified with a schema and a database.
+type TableName struct {
+	Database string
+	Schema   string
+	Name     string
+}
+
+// ParseTableName parses a table, schema.table or database.schema.table name.
+func ParseTableName(tableName string) (TableName, error) {
+	parts := strings.Split(tableName, tableNameSeparator)
+	if len(parts) > maxTableNameParts {
+		return TableName{}, fmt.Errorf("parse %q: %w", tableName, ErrTooManyTableNameParts)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return TableName{}, fmt.Errorf("parse %q: %w", tableName, ErrEmptyTableNamePart)
+		}
+
+		if len(part) > maxIdentifierLength {
+			return TableName{}, fmt.Errorf("parse %q: %w", tableName, ErrTableNamePartTooLong)
+		}
+	}
+
+	// fill the parts from the end, so that the last one is always the table name
+	var name TableName
+	fields := []*string{&name.Name, &name.Schema, &name.Database}
+	for i := range parts {
+		*fields[i] = parts[len(parts)-1-i]
+	}
+
+	return name, nil
+}
+
+// Parts returns the non-empty parts of the name, from the database to the table.
+func (t TableName) Parts() []string {
+	parts := make([]string, 0, maxTableNameParts)
+	for _, part := range []string{t.Database, t.Schema, t.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// String returns the name with its parts joined with dots.
+func (t TableName) String() string {
+	return strings.Join(t.Parts(), tableNameSeparator)
+}
@@ -0,0 +1,84 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coltypes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		tableName string
+		want      TableName
+		wantErr   error
+	}{
+		{
+			name:      "table",
+			tableName: "users",
+			want:      TableName{Name: "users"},
+		},
+		{
+			name:      "schema and table",
+			tableName: "public.users",
+			want:      TableName{Schema: "public", Name: "users"},
+		},
+		{
+			name:      "database, schema and table",
+			tableName: "materialize.public.users",
+			want:      TableName{Database: "materialize", Schema: "public", Name: "users"},
+		},
+		{
+			name:      "too many parts",
+			tableName: "materialize.public.users.id",
+			wantErr:   ErrTooManyTableNameParts,
+		},
+		{
+			name:      "empty part",
+			tableName: "public.",
+			wantErr:   ErrEmptyTableNamePart,
+		},
+		{
+			name:      "part too long",
+			tableName: "public." + strings.Repeat("a", 64),
+			wantErr:   ErrTableNamePartTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseTableName(tt.tableName)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseTableName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseTableName() = %+v, want %+v", got, tt.want)
+			}
+
+			if tt.wantErr == nil && got.String() != tt.tableName {
+				t.Errorf("TableName.String() = %q, want %q", got.String(), tt.tableName)
+			}
+		})
+	}
+}
@@ -15,33 +15,363 @@
 package config
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 )
 
 const (
 	// KeyURL is the config name for a connection URL.
 	KeyURL = "url"
+	// KeyHost is the config name for a host, used instead of a connection URL.
+	KeyHost = "host"
+	// KeyPort is the config name for a port, used instead of a connection URL.
+	KeyPort = "port"
+	// KeyUser is the config name for a user, used instead of a connection URL.
+	KeyUser = "user"
+	// KeyPassword is the config name for a password, used instead of a connection URL.
+	KeyPassword = "password"
+	// KeyPasswordFile is the config name for a path to a file containing a password.
+	KeyPasswordFile = "passwordFile"
+	// KeyDatabase is the config name for a database, used instead of a connection URL.
+	KeyDatabase = "database"
+	// KeyOptions is the config name for command-line options sent to the server at connection start.
+	KeyOptions = "options"
 	// KeyTable is the config name for a table.
 	KeyTable = "table"
 	// KeyKey is the config name for a key.
 	KeyKey = "key"
+	// KeyUpdateMode is the config name for an update mode.
+	KeyUpdateMode = "updateMode"
+	// KeyTableUpdateModes is the config name for per-table update modes.
+	KeyTableUpdateModes = "tableUpdateModes"
+	// KeyWriteMode is the config name for a write mode.
+	KeyWriteMode = "writeMode"
+	// KeyNestedMode is the config name for a nested mode.
+	KeyNestedMode = "nestedMode"
+	// KeyFlattenSeparator is the config name for a separator of flattened column names.
+	KeyFlattenSeparator = "flattenSeparator"
+	// KeyFlattenMaxDepth is the config name for a maximum depth of flattening.
+	KeyFlattenMaxDepth = "flattenMaxDepth"
+	// KeyIdentifierCase is the config name for an identifier case.
+	KeyIdentifierCase = "identifierCase"
+	// KeyRoutingMetadataKeys is the config name for metadata keys used to route records to tables.
+	KeyRoutingMetadataKeys = "routingMetadataKeys"
+	// KeyCollectionPrefix is the config name for a prefix of table names routed by collections.
+	KeyCollectionPrefix = "collectionPrefix"
+	// KeyCollectionSuffix is the config name for a suffix of table names routed by collections.
+	KeyCollectionSuffix = "collectionSuffix"
+	// KeyCollectionMapping is the config name for a mapping of collections to table names.
+	KeyCollectionMapping = "collectionMapping"
+	// KeyAutoCreateTable is the config name for a flag that enables automatic creation of missing tables.
+	KeyAutoCreateTable = "autoCreateTable"
+	// KeyUnknownColumns is the config name for a policy of payload fields that don't exist in the table.
+	KeyUnknownColumns = "unknownColumns"
+	// KeyMaxConnections is the config name for a maximum number of connections in the pool.
+	KeyMaxConnections = "maxConnections"
+	// KeyMaxRetries is the config name for a maximum number of retries of statements failed with transient errors.
+	KeyMaxRetries = "maxRetries"
+	// KeyRetryBackoff is the config name for an initial backoff between retries.
+	KeyRetryBackoff = "retryBackoff"
+	// KeyTLSMode is the config name for a TLS mode.
+	KeyTLSMode = "tlsMode"
+	// KeyTLSCACert is the config name for a CA certificate, either inline PEM or a path to a PEM file.
+	KeyTLSCACert = "tlsCACert"
+	// KeyTLSClientCert is the config name for a client certificate, either inline PEM or a path to a PEM file.
+	KeyTLSClientCert = "tlsClientCert"
+	// KeyTLSClientKey is the config name for a client key, either inline PEM or a path to a PEM file.
+	KeyTLSClientKey = "tlsClientKey"
+	// KeyTLSServerName is the config name for a server name used to verify the server certificate.
+	KeyTLSServerName = "tlsServerName"
+	// KeySessionSettings is the config name prefix of session settings, e.g. sessionSettings.cluster.
+	KeySessionSettings = "sessionSettings"
 )
 
+const (
+	// MetadataTable is a record metadata key for the name of the table the record is written to.
+	MetadataTable = "materialize.table"
+)
+
+// defaultRoutingMetadataKeys are the metadata keys used to route records to tables by default.
+var defaultRoutingMetadataKeys = []string{MetadataTable}
+
+const (
+	// listSeparator separates items of config values that accept a list.
+	listSeparator = ","
+	// mapSeparator separates keys and values of config values that accept a map.
+	mapSeparator = ":"
+)
+
+// UpdateMode defines how the connector applies updates.
+type UpdateMode string
+
+const (
+	// UpdateModeUpdate updates the rows matching the key and does nothing if there are none.
+	UpdateModeUpdate UpdateMode = "update"
+	// UpdateModeUpsert updates the rows matching the key and inserts the record if there are none.
+	UpdateModeUpsert UpdateMode = "upsert"
+)
+
+// WriteMode defines how the connector writes creates, snapshots and updates.
+type WriteMode string
+
+const (
+	// WriteModeAppend inserts creates and snapshots and applies updates according to the UpdateMode.
+	WriteModeAppend WriteMode = "append"
+	// WriteModeReplace deletes the rows matching the key and then inserts the record,
+	// so that there is exactly one row per key. The two statements aren't atomic.
+	WriteModeReplace WriteMode = "replace"
+)
+
+// NestedMode defines how the connector writes nested objects of payloads.
+type NestedMode string
+
+const (
+	// NestedModeStringify writes nested objects to a single column, encoded according to the column type.
+	NestedModeStringify NestedMode = "stringify"
+	// NestedModeFlatten expands nested objects into columns named after the path to the fields,
+	// e.g. the field "city" of the object "address" is written to the column "address_city".
+	NestedModeFlatten NestedMode = "flatten"
+)
+
+const (
+	// defaultFlattenSeparator is the default separator of flattened column names.
+	defaultFlattenSeparator = "_"
+	// defaultMaxConnections is the default maximum number of connections in the pool.
+	defaultMaxConnections = 4
+	// defaultMaxRetries is the default maximum number of retries of statements failed with transient errors.
+	defaultMaxRetries = 3
+	// defaultRetryBackoff is the default initial backoff between retries.
+	defaultRetryBackoff = time.Second
+)
+
+// UnknownColumnPolicy defines how the connector treats payload fields that don't exist in the target table.
+type UnknownColumnPolicy string
+
+const (
+	// UnknownColumnPolicyFail fails the record.
+	UnknownColumnPolicyFail UnknownColumnPolicy = "fail"
+	// UnknownColumnPolicyDrop drops the fields and logs them.
+	UnknownColumnPolicyDrop UnknownColumnPolicy = "drop"
+	// UnknownColumnPolicyAdd adds the columns to the table, with data types inferred from the values.
+	UnknownColumnPolicyAdd UnknownColumnPolicy = "add"
+)
+
+// IdentifierCase defines how the connector treats the case of table, key and column names.
+type IdentifierCase string
+
+const (
+	// IdentifierCaseLower folds names to lower case, the same way Materialize folds unquoted identifiers.
+	IdentifierCaseLower IdentifierCase = "lower"
+	// IdentifierCasePreserve keeps names exactly as they are,
+	// which is needed for tables and columns created with quoted mixed-case identifiers.
+	IdentifierCasePreserve IdentifierCase = "preserve"
+)
+
+// Apply returns the name with the identifier case applied.
+// The zero value of IdentifierCase behaves as IdentifierCaseLower.
+func (ic IdentifierCase) Apply(name string) string {
+	if ic == IdentifierCasePreserve {
+		return name
+	}
+
+	return strings.ToLower(name)
+}
+
+// templateActionDelimiter marks a table config value as a Go template.
+const templateActionDelimiter = "{{"
+
 // Config represents configuration needed for Materialize.
 type Config struct {
-	URL string `validate:"required,url"`
-	// The maximum identifier length is 63.
-	// See https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-IDENTIFIERS.
-	Table string `validate:"required,max=63"`
-	Key   string `validate:"required,max=63"`
+	// URL is the connection URL. It's mutually exclusive with the discrete connection parameters,
+	// Host, Port, User, Password, PasswordFile, Database and Options, see ConnectionString.
+	URL  string `key:"url" validate:"omitempty,url"`
+	Host string `key:"host"`
+	Port int    `key:"port" validate:"omitempty,gte=1,lte=65535"`
+	User string `key:"user"`
+	// Password and PasswordFile are mutually exclusive, the file contains the password only, see ResolvePassword.
+	Password     string `key:"password"`
+	PasswordFile string `key:"passwordFile"`
+	Database     string `key:"database"`
+	// Options are command-line options sent to the server at connection start, e.g. --cluster=quickstart.
+	Options string `key:"options"`
+	// Table is either a table name or a Go template that renders it, see TableTemplate.
+	// The length of the table name is validated separately, since it doesn't apply to templates.
+	Table string `key:"table" validate:"required"`
+	// Key is an ordered list of key column names.
+	Key []string `key:"key" validate:"required,dive,max=63"`
+	// UpdateMode is the update mode used for tables without an entry in TableUpdateModes.
+	UpdateMode UpdateMode `key:"updateMode" validate:"oneof=update upsert"`
+	// TableUpdateModes maps table names to their update modes.
+	TableUpdateModes map[string]UpdateMode `key:"tableUpdateModes" validate:"dive,keys,table,endkeys,oneof=update upsert"`
+	WriteMode        WriteMode             `key:"writeMode" validate:"oneof=append replace"`
+	NestedMode       NestedMode            `key:"nestedMode" validate:"oneof=stringify flatten"`
+	FlattenSeparator string                `key:"flattenSeparator" validate:"required"`
+	// FlattenMaxDepth is the maximum number of nesting levels expanded into columns,
+	// objects nested deeper are written to a single column. Zero means there is no limit.
+	FlattenMaxDepth int            `key:"flattenMaxDepth" validate:"gte=0"`
+	IdentifierCase  IdentifierCase `key:"identifierCase" validate:"oneof=lower preserve"`
+	// RoutingMetadataKeys is an ordered list of metadata keys that contain the table name of a record,
+	// the first key present in the metadata wins.
+	RoutingMetadataKeys []string `key:"routingMetadataKeys" validate:"dive,oneof=materialize.table opencdc.collection"`
+	CollectionPrefix    string   `key:"collectionPrefix"`
+	CollectionSuffix    string   `key:"collectionSuffix"`
+	// CollectionMapping maps collections to table names, which are used without the prefix and suffix.
+	CollectionMapping map[string]string `key:"collectionMapping" validate:"dive,keys,required,endkeys,required,table"`
+	// AutoCreateTable makes the connector create missing tables with column types inferred from the records.
+	AutoCreateTable bool `key:"autoCreateTable"`
+	// UnknownColumnPolicy is applied to payload fields that don't exist in the target table.
+	UnknownColumnPolicy UnknownColumnPolicy `key:"unknownColumns" validate:"oneof=fail drop add"`
+	// MaxConnections is the maximum number of connections in the pool,
+	// which is also the maximum number of tables written concurrently.
+	MaxConnections int32 `key:"maxConnections" validate:"gte=1"`
+	// MaxRetries is the maximum number of retries of a statement that fails with a transient error,
+	// e.g. because the connection dropped. Zero disables retries.
+	MaxRetries int `key:"maxRetries" validate:"gte=0"`
+	// RetryBackoff is the backoff before the first retry, it's doubled before every next one.
+	RetryBackoff time.Duration `key:"retryBackoff" validate:"gte=1ms"`
+	// TLSMode overrides the TLS settings of the URL, if it's set.
+	TLSMode TLSMode `key:"tlsMode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	// TLSCACert, TLSClientCert and TLSClientKey are either inline PEM or paths to PEM files.
+	TLSCACert     string `key:"tlsCACert"`
+	TLSClientCert string `key:"tlsClientCert"`
+	TLSClientKey  string `key:"tlsClientKey"`
+	// TLSServerName is the name the server certificate is verified against, it defaults to the host.
+	TLSServerName string `key:"tlsServerName"`
+	// SessionSettings maps names of session settings, e.g. cluster or search_path, to their values,
+	// which are set on every connection, see SessionSettingNames.
+	SessionSettings map[string]string `key:"sessionSettings"`
 }
 
 // Parse attempts to parse a provided map[string]string into a Config struct.
 func Parse(cfg map[string]string) (Config, error) {
+	identifierCase := IdentifierCaseLower
+	if value := cfg[KeyIdentifierCase]; value != "" {
+		identifierCase = IdentifierCase(value)
+	}
+
+	// the identifier case is applied to a table name, templates are kept as is,
+	// the identifier case is applied to the names they render instead
+	table := cfg[KeyTable]
+	if !isTemplate(table) {
+		table = identifierCase.Apply(table)
+	}
+
 	config := Config{
-		URL:   cfg[KeyURL],
-		Table: strings.ToLower(cfg[KeyTable]),
-		Key:   strings.ToLower(cfg[KeyKey]),
+		URL:                 cfg[KeyURL],
+		Host:                cfg[KeyHost],
+		User:                cfg[KeyUser],
+		Password:            cfg[KeyPassword],
+		PasswordFile:        cfg[KeyPasswordFile],
+		Database:            cfg[KeyDatabase],
+		Options:             cfg[KeyOptions],
+		Table:               table,
+		Key:                 parseList(identifierCase.Apply(cfg[KeyKey])),
+		UpdateMode:          UpdateModeUpdate,
+		WriteMode:           WriteModeAppend,
+		NestedMode:          NestedModeStringify,
+		FlattenSeparator:    defaultFlattenSeparator,
+		IdentifierCase:      identifierCase,
+		RoutingMetadataKeys: defaultRoutingMetadataKeys,
+		CollectionPrefix:    cfg[KeyCollectionPrefix],
+		CollectionSuffix:    cfg[KeyCollectionSuffix],
+		TLSMode:             TLSMode(cfg[KeyTLSMode]),
+		TLSCACert:           cfg[KeyTLSCACert],
+		TLSClientCert:       cfg[KeyTLSClientCert],
+		TLSClientKey:        cfg[KeyTLSClientKey],
+		TLSServerName:       cfg[KeyTLSServerName],
+		UnknownColumnPolicy: UnknownColumnPolicyFail,
+		MaxConnections:      defaultMaxConnections,
+		MaxRetries:          defaultMaxRetries,
+		RetryBackoff:        defaultRetryBackoff,
+	}
+
+	if unknownColumnPolicy := cfg[KeyUnknownColumns]; unknownColumnPolicy != "" {
+		config.UnknownColumnPolicy = UnknownColumnPolicy(unknownColumnPolicy)
+	}
+
+	if routingMetadataKeys := parseList(cfg[KeyRoutingMetadataKeys]); routingMetadataKeys != nil {
+		config.RoutingMetadataKeys = routingMetadataKeys
+	}
+
+	for collection, table := range parseMap(cfg[KeyCollectionMapping]) {
+		if config.CollectionMapping == nil {
+			config.CollectionMapping = make(map[string]string)
+		}
+
+		config.CollectionMapping[identifierCase.Apply(collection)] = identifierCase.Apply(table)
+	}
+
+	if nestedMode := cfg[KeyNestedMode]; nestedMode != "" {
+		config.NestedMode = NestedMode(nestedMode)
+	}
+
+	if flattenSeparator := cfg[KeyFlattenSeparator]; flattenSeparator != "" {
+		config.FlattenSeparator = flattenSeparator
+	}
+
+	if port := cfg[KeyPort]; port != "" {
+		var err error
+		if config.Port, err = strconv.Atoi(port); err != nil {
+			return Config{}, fmt.Errorf("%q config value must be an integer", KeyPort)
+		}
+	}
+
+	if flattenMaxDepth := cfg[KeyFlattenMaxDepth]; flattenMaxDepth != "" {
+		var err error
+		if config.FlattenMaxDepth, err = strconv.Atoi(flattenMaxDepth); err != nil {
+			return Config{}, fmt.Errorf("%q config value must be an integer", KeyFlattenMaxDepth)
+		}
+	}
+
+	if maxConnections := cfg[KeyMaxConnections]; maxConnections != "" {
+		value, err := strconv.ParseInt(maxConnections, 10, 32)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value must be an integer", KeyMaxConnections)
+		}
+
+		config.MaxConnections = int32(value)
+	}
+
+	if maxRetries := cfg[KeyMaxRetries]; maxRetries != "" {
+		var err error
+		if config.MaxRetries, err = strconv.Atoi(maxRetries); err != nil {
+			return Config{}, fmt.Errorf("%q config value must be an integer", KeyMaxRetries)
+		}
+	}
+
+	if retryBackoff := cfg[KeyRetryBackoff]; retryBackoff != "" {
+		var err error
+		if config.RetryBackoff, err = time.ParseDuration(retryBackoff); err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a duration", KeyRetryBackoff)
+		}
+	}
+
+	if autoCreateTable := cfg[KeyAutoCreateTable]; autoCreateTable != "" {
+		var err error
+		if config.AutoCreateTable, err = strconv.ParseBool(autoCreateTable); err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a boolean", KeyAutoCreateTable)
+		}
+	}
+
+	if writeMode := cfg[KeyWriteMode]; writeMode != "" {
+		config.WriteMode = WriteMode(writeMode)
+	}
+
+	if updateMode := cfg[KeyUpdateMode]; updateMode != "" {
+		config.UpdateMode = UpdateMode(updateMode)
+	}
+
+	config.SessionSettings = parseSessionSettings(cfg)
+
+	for table, updateMode := range parseMap(cfg[KeyTableUpdateModes]) {
+		if config.TableUpdateModes == nil {
+			config.TableUpdateModes = make(map[string]UpdateMode)
+		}
+
+		config.TableUpdateModes[identifierCase.Apply(table)] = UpdateMode(updateMode)
 	}
 
 	if err := config.Validate(); err != nil {
@@ -50,3 +380,82 @@ func Parse(cfg map[string]string) (Config, error) {
 
 	return config, nil
 }
+
+// TableTemplate parses the Table as a Go template. It returns nil if the Table is a plain table name.
+//
+// The template is evaluated against the record's metadata, key and payload,
+// for example {{ index .Metadata "opencdc.collection" }}_raw.
+func (c Config) TableTemplate() (*template.Template, error) {
+	if !isTemplate(c.Table) {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(KeyTable).Option("missingkey=error").Parse(c.Table)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// RoutingKeys returns the RoutingMetadataKeys, or the default ones if they're not set.
+func (c Config) RoutingKeys() []string {
+	if len(c.RoutingMetadataKeys) == 0 {
+		return defaultRoutingMetadataKeys
+	}
+
+	return c.RoutingMetadataKeys
+}
+
+// CollectionTable returns the table name for the collection,
+// either mapped with the CollectionMapping or surrounded with the CollectionPrefix and CollectionSuffix.
+func (c Config) CollectionTable(collection string) string {
+	if table, ok := c.CollectionMapping[c.IdentifierCase.Apply(collection)]; ok {
+		return table
+	}
+
+	return c.CollectionPrefix + collection + c.CollectionSuffix
+}
+
+// UpdateModeOf returns the update mode for the table.
+func (c Config) UpdateModeOf(table string) UpdateMode {
+	if updateMode, ok := c.TableUpdateModes[table]; ok {
+		return updateMode
+	}
+
+	return c.UpdateMode
+}
+
+// isTemplate reports whether the value is a Go template.
+func isTemplate(value string) bool {
+	return strings.Contains(value, templateActionDelimiter)
+}
+
+// parseList splits a comma-separated config value into a list of trimmed, non-empty items.
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseMap splits a comma-separated list of colon-separated key-value pairs into a map.
+// Items without a separator are mapped to an empty value, so they fail the validation of the value.
+func parseMap(value string) map[string]string {
+	items := parseList(value)
+	if len(items) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(items))
+	for _, item := range items {
+		key, value, _ := strings.Cut(item, mapSeparator)
+		result[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return result
+}
@@ -17,6 +17,7 @@ package config
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParse(t *testing.T) {
@@ -35,9 +36,285 @@ func TestParse(t *testing.T) {
 				"key":   "id",
 			},
 			want: Config{
-				URL:   "postgres://materialize@localhost:6875/materialize?sslmode=disable",
-				Table: "footable",
-				Key:   "id",
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, composite key",
+			cfg: map[string]string{
+				"url":   "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table": "footable",
+				"key":   "tenant_id, ID,",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"tenant_id", "id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, update modes",
+			cfg: map[string]string{
+				"url":              "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":            "footable",
+				"key":              "id",
+				"updateMode":       "upsert",
+				"tableUpdateModes": "Orders:update, events:upsert",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpsert,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+				TableUpdateModes: map[string]UpdateMode{
+					"orders": UpdateModeUpdate,
+					"events": UpdateModeUpsert,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, replace write mode",
+			cfg: map[string]string{
+				"url":       "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":     "footable",
+				"key":       "id",
+				"writeMode": "replace",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeReplace,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, add unknown columns",
+			cfg: map[string]string{
+				"url":            "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":          "footable",
+				"key":            "id",
+				"unknownColumns": "add",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyAdd,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, auto create table",
+			cfg: map[string]string{
+				"url":             "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":           "footable",
+				"key":             "id",
+				"autoCreateTable": "true",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				AutoCreateTable:     true,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, flatten nested mode",
+			cfg: map[string]string{
+				"url":              "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":            "footable",
+				"key":              "id",
+				"nestedMode":       "flatten",
+				"flattenSeparator": "__",
+				"flattenMaxDepth":  "2",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeFlatten,
+				FlattenSeparator:    "__",
+				FlattenMaxDepth:     2,
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, preserve identifier case",
+			cfg: map[string]string{
+				"url":              "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":            "FooTable",
+				"key":              "TenantID,ID",
+				"tableUpdateModes": "Orders:upsert",
+				"identifierCase":   "preserve",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "FooTable",
+				Key:                 []string{"TenantID", "ID"},
+				UpdateMode:          UpdateModeUpdate,
+				TableUpdateModes:    map[string]UpdateMode{"Orders": UpdateModeUpsert},
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCasePreserve,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, table template",
+			cfg: map[string]string{
+				"url":   "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table": `{{ index .Metadata "opencdc.collection" }}_Raw`,
+				"key":   "id",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               `{{ index .Metadata "opencdc.collection" }}_Raw`,
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, collection routing",
+			cfg: map[string]string{
+				"url":                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":               "footable",
+				"key":                 "id",
+				"routingMetadataKeys": "opencdc.collection, materialize.table",
+				"collectionPrefix":    "raw_",
+				"collectionSuffix":    "_v1",
+				"collectionMapping":   "Orders:sales_orders",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"opencdc.collection", "materialize.table"},
+				CollectionPrefix:    "raw_",
+				CollectionSuffix:    "_v1",
+				CollectionMapping:   map[string]string{"orders": "sales_orders"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, qualified table",
+			cfg: map[string]string{
+				"url":               "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":             "materialize.public.footable",
+				"key":               "id",
+				"collectionMapping": "orders:sales.orders",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "materialize.public.footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+				CollectionMapping:   map[string]string{"orders": "sales.orders"},
 			},
 			wantErr: false,
 		},
@@ -49,7 +326,7 @@ func TestParse(t *testing.T) {
 			},
 			want:        Config{},
 			wantErr:     true,
-			expectedErr: "\"url\" config value must be set",
+			expectedErr: "\"url\" or \"host\" config value must be set",
 		},
 		{
 			name: "missing table",
@@ -93,6 +370,31 @@ func TestParse(t *testing.T) {
 			wantErr:     true,
 			expectedErr: "\"table\" config value is too long",
 		},
+		{
+			name: "table name has too many parts",
+			cfg: map[string]string{
+				"url":   "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table": "materialize.public.footable.id",
+				"key":   "id",
+			},
+			want:    Config{},
+			wantErr: true,
+			expectedErr: "\"table\" config value must be a valid table name: " +
+				"parse \"materialize.public.footable.id\": " +
+				"table name must be table, schema.table or database.schema.table",
+		},
+		{
+			name: "collection mapping to an invalid table name",
+			cfg: map[string]string{
+				"url":               "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":             "footable",
+				"key":               "id",
+				"collectionMapping": "orders:sales..orders",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"collectionMapping\" config value must contain valid table names",
+		},
 		{
 			name: "key name is too long",
 			cfg: map[string]string{
@@ -104,6 +406,271 @@ func TestParse(t *testing.T) {
 			wantErr:     true,
 			expectedErr: "\"key\" config value is too long",
 		},
+		{
+			name: "composite key name is too long",
+			cfg: map[string]string{
+				"url":   "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table": "footable",
+				"key":   "id,a_very_long_identifier_name_that_does_not_fit_within_the_limits_of_a_database",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"key\" config value is too long",
+		},
+		{
+			name: "invalid update mode",
+			cfg: map[string]string{
+				"url":        "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":      "footable",
+				"key":        "id",
+				"updateMode": "merge",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"updateMode\" config value must be one of: update, upsert",
+		},
+		{
+			name: "invalid write mode",
+			cfg: map[string]string{
+				"url":       "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":     "footable",
+				"key":       "id",
+				"writeMode": "overwrite",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"writeMode\" config value must be one of: append, replace",
+		},
+		{
+			name: "invalid nested mode",
+			cfg: map[string]string{
+				"url":        "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":      "footable",
+				"key":        "id",
+				"nestedMode": "expand",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"nestedMode\" config value must be one of: stringify, flatten",
+		},
+		{
+			name: "invalid flatten max depth",
+			cfg: map[string]string{
+				"url":             "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":           "footable",
+				"key":             "id",
+				"flattenMaxDepth": "deep",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"flattenMaxDepth\" config value must be an integer",
+		},
+		{
+			name: "negative flatten max depth",
+			cfg: map[string]string{
+				"url":             "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":           "footable",
+				"key":             "id",
+				"flattenMaxDepth": "-1",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"flattenMaxDepth\" config value must be greater than or equal to 0",
+		},
+		{
+			name: "successfull, max connections",
+			cfg: map[string]string{
+				"url":            "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":          "footable",
+				"key":            "id",
+				"maxConnections": "16",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      16,
+				MaxRetries:          3,
+				RetryBackoff:        time.Second,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfull, retries",
+			cfg: map[string]string{
+				"url":          "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":        "footable",
+				"key":          "id",
+				"maxRetries":   "0",
+				"retryBackoff": "250ms",
+			},
+			want: Config{
+				URL:                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				Table:               "footable",
+				Key:                 []string{"id"},
+				UpdateMode:          UpdateModeUpdate,
+				WriteMode:           WriteModeAppend,
+				NestedMode:          NestedModeStringify,
+				FlattenSeparator:    "_",
+				IdentifierCase:      IdentifierCaseLower,
+				UnknownColumnPolicy: UnknownColumnPolicyFail,
+				MaxConnections:      4,
+				RetryBackoff:        250 * time.Millisecond,
+				RoutingMetadataKeys: []string{"materialize.table"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid max retries",
+			cfg: map[string]string{
+				"url":        "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":      "footable",
+				"key":        "id",
+				"maxRetries": "-1",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"maxRetries\" config value must be greater than or equal to 0",
+		},
+		{
+			name: "invalid retry backoff",
+			cfg: map[string]string{
+				"url":          "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":        "footable",
+				"key":          "id",
+				"retryBackoff": "often",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"retryBackoff\" config value must be a duration",
+		},
+		{
+			name: "too short retry backoff",
+			cfg: map[string]string{
+				"url":          "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":        "footable",
+				"key":          "id",
+				"retryBackoff": "0s",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"retryBackoff\" config value must be greater than or equal to 1ms",
+		},
+		{
+			name: "invalid max connections",
+			cfg: map[string]string{
+				"url":            "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":          "footable",
+				"key":            "id",
+				"maxConnections": "many",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"maxConnections\" config value must be an integer",
+		},
+		{
+			name: "zero max connections",
+			cfg: map[string]string{
+				"url":            "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":          "footable",
+				"key":            "id",
+				"maxConnections": "0",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"maxConnections\" config value must be greater than or equal to 1",
+		},
+		{
+			name: "invalid table template",
+			cfg: map[string]string{
+				"url":   "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table": "{{ .Metadata",
+				"key":   "id",
+			},
+			want:    Config{},
+			wantErr: true,
+			expectedErr: "\"table\" config value must be a valid template: " +
+				"parse template: template: table:1: unclosed action",
+		},
+		{
+			name: "invalid routing metadata key",
+			cfg: map[string]string{
+				"url":                 "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":               "footable",
+				"key":                 "id",
+				"routingMetadataKeys": "opencdc.collection,table",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"routingMetadataKeys\" config value must be one of: materialize.table, opencdc.collection",
+		},
+		{
+			name: "invalid collection mapping",
+			cfg: map[string]string{
+				"url":               "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":             "footable",
+				"key":               "id",
+				"collectionMapping": "orders",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"collectionMapping\" config value must be set",
+		},
+		{
+			name: "invalid auto create table",
+			cfg: map[string]string{
+				"url":             "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":           "footable",
+				"key":             "id",
+				"autoCreateTable": "sometimes",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"autoCreateTable\" config value must be a boolean",
+		},
+		{
+			name: "invalid unknown column policy",
+			cfg: map[string]string{
+				"url":            "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":          "footable",
+				"key":            "id",
+				"unknownColumns": "ignore",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"unknownColumns\" config value must be one of: fail, drop, add",
+		},
+		{
+			name: "invalid identifier case",
+			cfg: map[string]string{
+				"url":            "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":          "footable",
+				"key":            "id",
+				"identifierCase": "upper",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"identifierCase\" config value must be one of: lower, preserve",
+		},
+		{
+			name: "invalid table update mode",
+			cfg: map[string]string{
+				"url":              "postgres://materialize@localhost:6875/materialize?sslmode=disable",
+				"table":            "footable",
+				"key":              "id",
+				"tableUpdateModes": "orders",
+			},
+			want:        Config{},
+			wantErr:     true,
+			expectedErr: "\"tableUpdateModes\" config value must be one of: update, upsert",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,148 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultPort is the port Materialize listens on for SQL connections by default.
+	defaultPort = 6875
+	// defaultDatabase is the database Materialize creates by default.
+	defaultDatabase = "materialize"
+)
+
+// dsnEscaper escapes the characters that have a special meaning within quoted values of a connection string.
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// ConnectionString returns the URL if it's set, otherwise it returns a keyword/value connection string
+// built from the discrete connection parameters, e.g. host='localhost' port='6875' dbname='materialize'.
+// The password isn't included in the connection string, see ResolvePassword.
+func (c Config) ConnectionString() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	port := c.Port
+	if port == 0 {
+		port = defaultPort
+	}
+
+	database := c.Database
+	if database == "" {
+		database = defaultDatabase
+	}
+
+	settings := [][2]string{
+		{"host", c.Host},
+		{"port", strconv.Itoa(port)},
+		{"user", c.User},
+		{"dbname", database},
+		{"options", c.Options},
+	}
+
+	parts := make([]string, 0, len(settings))
+	for _, setting := range settings {
+		if setting[1] != "" {
+			parts = append(parts, fmt.Sprintf("%s='%s'", setting[0], dsnEscaper.Replace(setting[1])))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ResolvePassword returns the Password, or the contents of the PasswordFile without surrounding whitespace.
+// It returns an empty string if neither is set, so that the password of the URL applies.
+func (c Config) ResolvePassword() (string, error) {
+	if c.PasswordFile == "" {
+		return c.Password, nil
+	}
+
+	content, err := os.ReadFile(c.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("read password file: %w", err)
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Secrets returns the credentials contained in the raw config, i.e. the password, the contents of the password file
+// and the password of the URL, both decoded and as it's written in the URL,
+// so that they can be redacted from error messages.
+func Secrets(cfg map[string]string) []string {
+	var secrets []string
+	if password := cfg[KeyPassword]; password != "" {
+		secrets = append(secrets, password)
+	}
+
+	if passwordFile := cfg[KeyPasswordFile]; passwordFile != "" {
+		if password, err := (Config{PasswordFile: passwordFile}).ResolvePassword(); err == nil && password != "" {
+			secrets = append(secrets, password)
+		}
+	}
+
+	if u, err := url.Parse(cfg[KeyURL]); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			_, escaped, _ := strings.Cut(u.User.String(), ":")
+			secrets = append(secrets, password, escaped)
+		}
+	}
+
+	return secrets
+}
+
+// validateConnection validates that the connection is configured either with the URL
+// or with the discrete connection parameters, and that the password is provided at most once.
+func (c Config) validateConnection() error {
+	discrete := []struct {
+		key string
+		set bool
+	}{
+		{KeyHost, c.Host != ""},
+		{KeyPort, c.Port != 0},
+		{KeyUser, c.User != ""},
+		{KeyPassword, c.Password != ""},
+		{KeyPasswordFile, c.PasswordFile != ""},
+		{KeyDatabase, c.Database != ""},
+		{KeyOptions, c.Options != ""},
+	}
+
+	var set []string
+	for _, param := range discrete {
+		if param.set {
+			set = append(set, strconv.Quote(param.key))
+		}
+	}
+
+	switch {
+	case c.URL != "" && len(set) > 0:
+		return fmt.Errorf("%q config value can't be used together with %s", KeyURL, strings.Join(set, ", "))
+	case c.URL == "" && c.Host == "":
+		return fmt.Errorf("%q or %q config value must be set", KeyURL, KeyHost)
+	case c.Password != "" && c.PasswordFile != "":
+		return fmt.Errorf("%q config value can't be used together with %q", KeyPassword, KeyPasswordFile)
+	}
+
+	if _, err := c.ResolvePassword(); err != nil {
+		return fmt.Errorf("%q config value must be a readable file: %w", KeyPasswordFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sessionSettingsPrefix starts the config names of session settings, e.g. sessionSettings.search_path.
+const sessionSettingsPrefix = KeySessionSettings + "."
+
+// sessionSettingName matches names of session settings, which may be qualified with a dot, e.g. mz_foo.bar.
+var sessionSettingName = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// parseSessionSettings collects the session settings from the config values named with the sessionSettings prefix,
+// e.g. sessionSettings.cluster. The names are folded to lower case, the same way Materialize treats them.
+func parseSessionSettings(cfg map[string]string) map[string]string {
+	var settings map[string]string
+	for key, value := range cfg {
+		name, ok := strings.CutPrefix(key, sessionSettingsPrefix)
+		if !ok {
+			continue
+		}
+
+		if settings == nil {
+			settings = make(map[string]string)
+		}
+
+		settings[strings.ToLower(name)] = value
+	}
+
+	return settings
+}
+
+// SessionSettingNames returns the names of the SessionSettings in alphabetical order,
+// which is the order they're set in.
+func (c Config) SessionSettingNames() []string {
+	names := make([]string, 0, len(c.SessionSettings))
+	for name := range c.SessionSettings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// validateSessionSettings validates the names of the SessionSettings,
+// which are used as identifiers in SET and SHOW statements.
+func (c Config) validateSessionSettings() error {
+	for _, name := range c.SessionSettingNames() {
+		if !sessionSettingName.MatchString(name) {
+			return fmt.Errorf("%q config value contains an invalid setting name %q", KeySessionSettings, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_SessionSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		cfg         map[string]string
+		want        map[string]string
+		expectedErr string
+	}{
+		{
+			name: "no settings",
+			cfg:  map[string]string{},
+			want: nil,
+		},
+		{
+			name: "settings",
+			cfg: map[string]string{
+				"sessionSettings.cluster":           "quickstart",
+				"sessionSettings.search_path":       "public, sales",
+				"sessionSettings.Application_Name":  "conduit",
+				"sessionSettings.statement_timeout": "30s",
+				"sessionSettings.mz_custom.setting": "on",
+			},
+			want: map[string]string{
+				"cluster":           "quickstart",
+				"search_path":       "public, sales",
+				"application_name":  "conduit",
+				"statement_timeout": "30s",
+				"mz_custom.setting": "on",
+			},
+		},
+		{
+			name: "invalid name",
+			cfg: map[string]string{
+				"sessionSettings.cluster; DROP TABLE users": "quickstart",
+			},
+			expectedErr: `"sessionSettings" config value contains an invalid setting name "cluster; drop table users"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := map[string]string{
+				KeyURL:   "postgres://materialize@localhost:6875/materialize",
+				KeyTable: "footable",
+				KeyKey:   "id",
+			}
+			for key, value := range tt.cfg {
+				cfg[key] = value
+			}
+
+			got, err := Parse(cfg)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				if !reflect.DeepEqual(got.SessionSettings, tt.want) {
+					t.Errorf("Parse() session settings = %v, want %v", got.SessionSettings, tt.want)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestConfig_SessionSettingNames(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{SessionSettings: map[string]string{"search_path": "public", "cluster": "quickstart", "role": "writer"}}
+
+	want := []string{"cluster", "role", "search_path"}
+	if got := cfg.SessionSettingNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SessionSettingNames() = %v, want %v", got, want)
+	}
+}
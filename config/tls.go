@@ -0,0 +1,232 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSMode defines whether and how connections are secured with TLS, the same way as the sslmode of libpq.
+type TLSMode string
+
+const (
+	// TLSModeDisable doesn't use TLS.
+	TLSModeDisable TLSMode = "disable"
+	// TLSModeAllow tries a connection without TLS first, and a connection with TLS if it fails.
+	TLSModeAllow TLSMode = "allow"
+	// TLSModePrefer tries a connection with TLS first, and a connection without TLS if it fails.
+	TLSModePrefer TLSMode = "prefer"
+	// TLSModeRequire uses TLS without verifying the server certificate,
+	// unless a CA certificate is provided, in which case it works as TLSModeVerifyCA.
+	TLSModeRequire TLSMode = "require"
+	// TLSModeVerifyCA uses TLS and verifies that the server certificate is signed by a trusted CA.
+	TLSModeVerifyCA TLSMode = "verify-ca"
+	// TLSModeVerifyFull uses TLS and verifies both the server certificate and the server host name.
+	TLSModeVerifyFull TLSMode = "verify-full"
+)
+
+// pemPrefix starts every PEM block, it tells inline PEM values from file paths.
+const pemPrefix = "-----BEGIN"
+
+// ErrNoCertificates occurs when a CA certificate value doesn't contain any PEM certificates.
+var ErrNoCertificates = errors.New("no PEM certificates found")
+
+// TLSConfigs returns the TLS configs of the connection attempts to the host, in the order they're tried.
+// A nil config stands for an attempt without TLS.
+// It returns nil if the TLSMode isn't set, so that the TLS settings of the URL apply.
+func (c Config) TLSConfigs(host string) ([]*tls.Config, error) {
+	if c.TLSMode == "" {
+		return nil, nil
+	}
+
+	if c.TLSMode == TLSModeDisable {
+		return []*tls.Config{nil}, nil
+	}
+
+	tlsConfig, err := c.tlsConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.TLSMode {
+	case TLSModeAllow:
+		return []*tls.Config{nil, tlsConfig}, nil
+	case TLSModePrefer:
+		return []*tls.Config{tlsConfig, nil}, nil
+	default:
+		return []*tls.Config{tlsConfig}, nil
+	}
+}
+
+// tlsConfig builds the TLS config of the TLSMode that uses TLS, with the certificates of the Config.
+func (c Config) tlsConfig(host string) (*tls.Config, error) {
+	serverName := c.TLSServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// only verify-full verifies the host name, the other modes either don't verify the certificate,
+		// or verify its chain with verifyChain
+		InsecureSkipVerify: c.TLSMode != TLSModeVerifyFull, //nolint:gosec // see above
+	}
+
+	if c.TLSClientCert != "" {
+		certificate, err := c.clientCertificate()
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if c.TLSCACert != "" {
+		rootCAs, err := c.rootCAs()
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	// verify-ca, and require with a CA certificate, verify the certificate chain without the host name
+	if c.TLSMode == TLSModeVerifyCA || (c.TLSMode == TLSModeRequire && c.TLSCACert != "") {
+		tlsConfig.VerifyPeerCertificate = verifyChain(tlsConfig.RootCAs)
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyChain returns a function that verifies the certificate chain presented by the server
+// against the root CAs, or the system ones if they're nil, without verifying the host name.
+func verifyChain(rootCAs *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server presented no certificates")
+		}
+
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, rawCert := range rawCerts {
+			cert, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				return fmt.Errorf("parse server certificate: %w", err)
+			}
+
+			certs[i] = cert
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         rootCAs,
+			Intermediates: intermediates,
+		})
+		if err != nil {
+			return fmt.Errorf("verify server certificate: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// rootCAs loads the CA certificates of the TLSCACert into a pool.
+func (c Config) rootCAs() (*x509.CertPool, error) {
+	caCert, err := loadPEM(c.TLSCACert)
+	if err != nil {
+		return nil, fmt.Errorf("%q config value must be a PEM certificate or a path to one: %w", KeyTLSCACert, err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("%q config value must be a PEM certificate or a path to one: %w",
+			KeyTLSCACert, ErrNoCertificates)
+	}
+
+	return rootCAs, nil
+}
+
+// clientCertificate loads the certificate and the key of the TLSClientCert and the TLSClientKey.
+func (c Config) clientCertificate() (tls.Certificate, error) {
+	certPEM, err := loadPEM(c.TLSClientCert)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%q config value must be a PEM certificate or a path to one: %w",
+			KeyTLSClientCert, err)
+	}
+
+	keyPEM, err := loadPEM(c.TLSClientKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%q config value must be a PEM key or a path to one: %w",
+			KeyTLSClientKey, err)
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%q and %q config values must be a matching certificate and key: %w",
+			KeyTLSClientCert, KeyTLSClientKey, err)
+	}
+
+	return certificate, nil
+}
+
+// validateTLS validates that the TLS parameters are used with a TLS mode and that their certificates can be loaded.
+func (c Config) validateTLS() error {
+	if c.TLSMode == "" || c.TLSMode == TLSModeDisable {
+		for _, param := range []struct{ key, value string }{
+			{KeyTLSCACert, c.TLSCACert},
+			{KeyTLSClientCert, c.TLSClientCert},
+			{KeyTLSClientKey, c.TLSClientKey},
+			{KeyTLSServerName, c.TLSServerName},
+		} {
+			if param.value != "" {
+				return fmt.Errorf("%q config value requires %q to be set to a mode that uses TLS", param.key, KeyTLSMode)
+			}
+		}
+
+		return nil
+	}
+
+	if (c.TLSClientCert == "") != (c.TLSClientKey == "") {
+		return fmt.Errorf("%q and %q config values must be set together", KeyTLSClientCert, KeyTLSClientKey)
+	}
+
+	// the host name doesn't matter, the certificates are loaded the same way for any host
+	_, err := c.tlsConfig("")
+
+	return err
+}
+
+// loadPEM returns the value if it's an inline PEM block, otherwise it reads the file the value points to.
+func loadPEM(value string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), pemPrefix) {
+		return []byte(value), nil
+	}
+
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	return data, nil
+}
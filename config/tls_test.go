@@ -0,0 +1,280 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// generateCertificate generates a self-signed certificate and its key, both PEM-encoded.
+func generateCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "materialize"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return string(certPEM), string(keyPEM)
+}
+
+func TestParse_TLS(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := generateCertificate(t)
+	otherCertPEM, _ := generateCertificate(t)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+
+	for path, content := range map[string]string{certPath: certPEM, keyPath: keyPEM} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		cfg         map[string]string
+		expectedErr string
+	}{
+		{
+			name: "inline pem",
+			cfg: map[string]string{
+				KeyTLSMode:       "verify-full",
+				KeyTLSCACert:     certPEM,
+				KeyTLSClientCert: certPEM,
+				KeyTLSClientKey:  keyPEM,
+				KeyTLSServerName: "materialize",
+			},
+		},
+		{
+			name: "file paths",
+			cfg: map[string]string{
+				KeyTLSMode:       "verify-ca",
+				KeyTLSCACert:     certPath,
+				KeyTLSClientCert: certPath,
+				KeyTLSClientKey:  keyPath,
+			},
+		},
+		{
+			name: "mode only",
+			cfg: map[string]string{
+				KeyTLSMode: "require",
+			},
+		},
+		{
+			name: "invalid mode",
+			cfg: map[string]string{
+				KeyTLSMode: "strict",
+			},
+			expectedErr: `"tlsMode" config value must be one of: disable, allow, prefer, require, verify-ca, verify-full`,
+		},
+		{
+			name: "ca cert without mode",
+			cfg: map[string]string{
+				KeyTLSCACert: certPEM,
+			},
+			expectedErr: `"tlsCACert" config value requires "tlsMode" to be set to a mode that uses TLS`,
+		},
+		{
+			name: "server name with disabled tls",
+			cfg: map[string]string{
+				KeyTLSMode:       "disable",
+				KeyTLSServerName: "materialize",
+			},
+			expectedErr: `"tlsServerName" config value requires "tlsMode" to be set to a mode that uses TLS`,
+		},
+		{
+			name: "client cert without key",
+			cfg: map[string]string{
+				KeyTLSMode:       "require",
+				KeyTLSClientCert: certPEM,
+			},
+			expectedErr: `"tlsClientCert" and "tlsClientKey" config values must be set together`,
+		},
+		{
+			name: "missing ca cert file",
+			cfg: map[string]string{
+				KeyTLSMode:   "verify-ca",
+				KeyTLSCACert: filepath.Join(dir, "missing.crt"),
+			},
+			expectedErr: `"tlsCACert" config value must be a PEM certificate or a path to one: read file`,
+		},
+		{
+			name: "ca cert without certificates",
+			cfg: map[string]string{
+				KeyTLSMode:   "verify-ca",
+				KeyTLSCACert: keyPath,
+			},
+			expectedErr: `"tlsCACert" config value must be a PEM certificate or a path to one: no PEM certificates found`,
+		},
+		{
+			name: "mismatched client cert and key",
+			cfg: map[string]string{
+				KeyTLSMode:       "require",
+				KeyTLSClientCert: otherCertPEM,
+				KeyTLSClientKey:  keyPEM,
+			},
+			expectedErr: `"tlsClientCert" and "tlsClientKey" config values must be a matching certificate and key`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := map[string]string{
+				KeyURL:   "postgres://materialize@localhost:6875/materialize",
+				KeyTable: "footable",
+				KeyKey:   "id",
+			}
+			for key, value := range tt.cfg {
+				cfg[key] = value
+			}
+
+			got, err := Parse(cfg)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				if got.TLSMode != TLSMode(tt.cfg[KeyTLSMode]) || got.TLSCACert != tt.cfg[KeyTLSCACert] {
+					t.Errorf("Parse() = %+v, want the TLS values of %v", got, tt.cfg)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestConfig_TLSConfigs(t *testing.T) {
+	t.Parallel()
+
+	certPEM, _ := generateCertificate(t)
+
+	tests := []struct {
+		name string
+		cfg  Config
+		// want lists whether each attempt uses TLS
+		want []bool
+		// wantVerify is whether the TLS attempt verifies the host name
+		wantVerify bool
+		// wantVerifyChain is whether the TLS attempt verifies the certificate chain without the host name
+		wantVerifyChain bool
+	}{
+		{name: "not set", cfg: Config{}, want: nil},
+		{name: "disable", cfg: Config{TLSMode: TLSModeDisable}, want: []bool{false}},
+		{name: "allow", cfg: Config{TLSMode: TLSModeAllow}, want: []bool{false, true}},
+		{name: "prefer", cfg: Config{TLSMode: TLSModePrefer}, want: []bool{true, false}},
+		{name: "require", cfg: Config{TLSMode: TLSModeRequire}, want: []bool{true}},
+		{
+			name:            "require with ca cert",
+			cfg:             Config{TLSMode: TLSModeRequire, TLSCACert: certPEM},
+			want:            []bool{true},
+			wantVerifyChain: true,
+		},
+		{
+			name:            "verify-ca",
+			cfg:             Config{TLSMode: TLSModeVerifyCA, TLSCACert: certPEM},
+			want:            []bool{true},
+			wantVerifyChain: true,
+		},
+		{
+			name:       "verify-full",
+			cfg:        Config{TLSMode: TLSModeVerifyFull, TLSCACert: certPEM},
+			want:       []bool{true},
+			wantVerify: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.cfg.TLSConfigs("materialize.example.com")
+			if err != nil {
+				t.Fatalf("TLSConfigs() error = %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("TLSConfigs() returned %d configs, want %d", len(got), len(tt.want))
+			}
+
+			for i, tlsConfig := range got {
+				if (tlsConfig != nil) != tt.want[i] {
+					t.Fatalf("TLSConfigs()[%d] = %v, want TLS %v", i, tlsConfig, tt.want[i])
+				}
+
+				if tlsConfig == nil {
+					continue
+				}
+
+				if tlsConfig.ServerName != "materialize.example.com" {
+					t.Errorf("TLSConfigs()[%d].ServerName = %q, want the host", i, tlsConfig.ServerName)
+				}
+
+				if tlsConfig.InsecureSkipVerify == tt.wantVerify {
+					t.Errorf("TLSConfigs()[%d].InsecureSkipVerify = %v, want %v",
+						i, tlsConfig.InsecureSkipVerify, !tt.wantVerify)
+				}
+
+				if (tlsConfig.VerifyPeerCertificate != nil) != tt.wantVerifyChain {
+					t.Errorf("TLSConfigs()[%d] verifies the chain = %v, want %v",
+						i, tlsConfig.VerifyPeerCertificate != nil, tt.wantVerifyChain)
+				}
+			}
+		})
+	}
+}
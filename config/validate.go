@@ -16,8 +16,11 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
@@ -35,8 +38,21 @@ func (c Config) Validate() error {
 		return errors.New("translator not found")
 	}
 
-	// init a new instance of a validator
+	// init a new instance of a validator that reports fields by their config names
 	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("key")
+	})
+
+	// register a validation of table names that may be qualified with a schema and a database
+	err := validate.RegisterValidation("table", func(fl validator.FieldLevel) bool {
+		_, err := coltypes.ParseTableName(fl.Field().String())
+
+		return err == nil
+	})
+	if err != nil {
+		return fmt.Errorf("register table validation: %w", err)
+	}
 
 	// register custom translations
 	if err := registerTranslations(validate, uniTranslator); err != nil {
@@ -44,16 +60,59 @@ func (c Config) Validate() error {
 	}
 
 	// collect all validation errors into one
+	var resultErr error
 	if err := validate.Struct(c); err != nil {
-		var resultErr error
 		validationErrors := err.(validator.ValidationErrors)
 		for _, validationError := range validationErrors {
 			resultErr = multierr.Append(resultErr, errors.New(
 				validationError.Translate(uniTranslator),
 			))
 		}
+	}
 
-		return resultErr
+	if err := c.validateConnection(); err != nil {
+		resultErr = multierr.Append(resultErr, err)
+	}
+
+	if err := c.validateSessionSettings(); err != nil {
+		resultErr = multierr.Append(resultErr, err)
+	}
+
+	if err := c.validateTable(); err != nil {
+		resultErr = multierr.Append(resultErr, err)
+	}
+
+	if err := c.validateTLS(); err != nil {
+		resultErr = multierr.Append(resultErr, err)
+	}
+
+	return resultErr
+}
+
+// validateTable validates the Table, which is either a valid template or a valid table name,
+// optionally qualified as schema.table or database.schema.table.
+func (c Config) validateTable() error {
+	if c.Table == "" {
+		// the required tag reports this case
+		return nil
+	}
+
+	tmpl, err := c.TableTemplate()
+	if err != nil {
+		return fmt.Errorf("%q config value must be a valid template: %w", KeyTable, err)
+	}
+
+	if tmpl != nil {
+		return nil
+	}
+
+	_, err = coltypes.ParseTableName(c.Table)
+	if errors.Is(err, coltypes.ErrTableNamePartTooLong) {
+		return fmt.Errorf("%q config value is too long", KeyTable)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%q config value must be a valid table name: %w", KeyTable, err)
 	}
 
 	return nil
@@ -65,9 +124,9 @@ func registerTranslations(validate *validator.Validate, uniTranslator ut.Transla
 	err := validate.RegisterTranslation("required", uniTranslator, func(ut ut.Translator) error {
 		return ut.Add("required", "\"{0}\" config value must be set", true)
 	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("required", fe.Field())
+		t, _ := ut.T("required", fieldName(fe))
 
-		return strings.ToLower(t)
+		return t
 	})
 	if err != nil {
 		return err
@@ -77,9 +136,57 @@ func registerTranslations(validate *validator.Validate, uniTranslator ut.Transla
 	err = validate.RegisterTranslation("url", uniTranslator, func(ut ut.Translator) error {
 		return ut.Add("url", "\"{0}\" config value must be a valid url", true)
 	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("url", fe.Field())
+		t, _ := ut.T("url", fieldName(fe))
+
+		return t
+	})
+	if err != nil {
+		return err
+	}
+
+	// register a custom translation for the oneof tag
+	err = validate.RegisterTranslation("oneof", uniTranslator, func(ut ut.Translator) error {
+		return ut.Add("oneof", "\"{0}\" config value must be one of: {1}", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("oneof", fieldName(fe), strings.ReplaceAll(fe.Param(), " ", ", "))
 
-		return strings.ToLower(t)
+		return t
+	})
+	if err != nil {
+		return err
+	}
+
+	// register a custom translation for the gte tag
+	err = validate.RegisterTranslation("gte", uniTranslator, func(ut ut.Translator) error {
+		return ut.Add("gte", "\"{0}\" config value must be greater than or equal to {1}", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("gte", fieldName(fe), fe.Param())
+
+		return t
+	})
+	if err != nil {
+		return err
+	}
+
+	// register a custom translation for the lte tag
+	err = validate.RegisterTranslation("lte", uniTranslator, func(ut ut.Translator) error {
+		return ut.Add("lte", "\"{0}\" config value must be less than or equal to {1}", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("lte", fieldName(fe), fe.Param())
+
+		return t
+	})
+	if err != nil {
+		return err
+	}
+
+	// register a custom translation for the table tag
+	err = validate.RegisterTranslation("table", uniTranslator, func(ut ut.Translator) error {
+		return ut.Add("table", "\"{0}\" config value must contain valid table names", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("table", fieldName(fe))
+
+		return t
 	})
 	if err != nil {
 		return err
@@ -89,9 +196,9 @@ func registerTranslations(validate *validator.Validate, uniTranslator ut.Transla
 	err = validate.RegisterTranslation("max", uniTranslator, func(ut ut.Translator) error {
 		return ut.Add("max", "\"{0}\" config value is too long", true)
 	}, func(ut ut.Translator, fe validator.FieldError) string {
-		t, _ := ut.T("max", fe.Field())
+		t, _ := ut.T("max", fieldName(fe))
 
-		return strings.ToLower(t)
+		return t
 	})
 	if err != nil {
 		return err
@@ -99,3 +206,11 @@ func registerTranslations(validate *validator.Validate, uniTranslator ut.Transla
 
 	return nil
 }
+
+// fieldName returns the name of the field that failed validation.
+// Items of list fields are reported with the name of the list itself.
+func fieldName(fe validator.FieldError) string {
+	name, _, _ := strings.Cut(fe.Field(), "[")
+
+	return name
+}
@@ -0,0 +1,86 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"slices"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// insertRow is a single row ready to be inserted into a table.
+type insertRow struct {
+	table   string
+	columns []string
+	values  []any
+	// snapshot is true if the row comes from a snapshot record and is going to be bulk-loaded with COPY.
+	snapshot bool
+}
+
+// insertBatch collects rows of consecutive records that can be written
+// with a single INSERT statement, or a single COPY for snapshot records.
+type insertBatch struct {
+	// start is the index of the first record of the batch within the written records.
+	start    int
+	table    string
+	columns  []string
+	rows     [][]any
+	snapshot bool
+	// records are the records the rows were prepared from, they're needed to prepare the rows once again.
+	records []opencdc.Record
+	// indexes are the indexes of the records within the written records.
+	indexes []int
+}
+
+// accepts reports whether the row can be added to the batch,
+// that is the batch is empty or the row is of the same kind
+// and targets the same table with the same columns.
+func (b *insertBatch) accepts(row insertRow) bool {
+	if len(b.rows) == 0 {
+		return true
+	}
+
+	return b.snapshot == row.snapshot && b.table == row.table && slices.Equal(b.columns, row.columns)
+}
+
+// adjoins reports whether the record with the index directly follows the last record of the batch
+// among all the written records, or the batch is empty.
+func (b *insertBatch) adjoins(index int) bool {
+	return len(b.indexes) == 0 || b.indexes[len(b.indexes)-1]+1 == index
+}
+
+// add appends the row of the record with the provided index to the batch.
+func (b *insertBatch) add(index int, record opencdc.Record, row insertRow) {
+	if len(b.rows) == 0 {
+		b.start = index
+		b.table = row.table
+		b.columns = row.columns
+		b.snapshot = row.snapshot
+	}
+
+	b.rows = append(b.rows, row.values)
+	b.records = append(b.records, record)
+	b.indexes = append(b.indexes, index)
+}
+
+// reset empties the batch.
+func (b *insertBatch) reset() {
+	b.table = ""
+	b.columns = nil
+	b.rows = nil
+	b.snapshot = false
+	b.records = nil
+	b.indexes = nil
+}
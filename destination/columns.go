@@ -0,0 +1,177 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
+	"github.com/conduitio-labs/conduit-connector-materialize/config"
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// SQLSTATE codes of errors returned by ALTER TABLE ... ADD COLUMN.
+	// See https://www.postgresql.org/docs/current/errcodes-appendix.html.
+	sqlStateDuplicateColumn     = "42701"
+	sqlStateFeatureNotSupported = "0A000"
+	sqlStateSyntaxError         = "42601"
+)
+
+// convertPayload creates the table if it's missing, applies the unknown column policy to the record's payload
+// and converts it according to the column types of the table.
+func (d *Destination) convertPayload(
+	ctx context.Context, tableName string, record opencdc.Record, payload opencdc.StructuredData,
+) (opencdc.StructuredData, error) {
+	if err := d.createTableIfMissing(ctx, tableName, record, payload); err != nil {
+		return nil, err
+	}
+
+	payload, err := d.applyUnknownColumnPolicy(ctx, tableName, record, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.convertStructureData(ctx, tableName, payload)
+}
+
+// applyUnknownColumnPolicy handles the payload fields that don't exist in the table
+// according to the config.UnknownColumnPolicy.
+func (d *Destination) applyUnknownColumnPolicy(
+	ctx context.Context, tableName string, record opencdc.Record, payload opencdc.StructuredData,
+) (opencdc.StructuredData, error) {
+	fields := make([]string, 0, len(payload))
+	for field := range payload {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	unknown, first, err := d.columnTypes.UnknownColumns(ctx, tableName, fields)
+	if err != nil {
+		return nil, fmt.Errorf("get unknown columns: %w", err)
+	}
+
+	if len(unknown) == 0 {
+		return payload, nil
+	}
+
+	switch d.config.UnknownColumnPolicy {
+	case config.UnknownColumnPolicyDrop:
+		// warn once per table and set of columns, the same fields of the following records are dropped quietly
+		event := sdk.Logger(ctx).Debug()
+		if first {
+			event = sdk.Logger(ctx).Warn()
+		}
+
+		event.
+			Str("table", tableName).
+			Strs("fields", unknown).
+			Msg("dropping payload fields that don't exist in the table")
+
+		result := make(opencdc.StructuredData, len(payload))
+		for field, value := range payload {
+			result[field] = value
+		}
+
+		for _, field := range unknown {
+			delete(result, field)
+		}
+
+		return result, nil
+
+	case config.UnknownColumnPolicyAdd:
+		if err := d.addColumns(ctx, tableName, unknown, record, payload); err != nil {
+			return nil, err
+		}
+
+		return payload, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownColumns, strings.Join(unknown, ", "))
+	}
+}
+
+// addColumns adds the columns to the table and refreshes the column types of the table.
+// Data types of the columns are taken from the payload schema attached to the record,
+// or inferred from the payload values if the schema lacks the fields.
+//
+// Columns added in the meantime by someone else are skipped. If Materialize rejects
+// the ALTER TABLE statement as unsupported, ErrSchemaEvolutionUnsupported is returned.
+func (d *Destination) addColumns(
+	ctx context.Context, tableName string, columns []string, record opencdc.Record, payload opencdc.StructuredData,
+) error {
+	table, err := coltypes.ParseTableName(tableName)
+	if err != nil {
+		return err
+	}
+
+	schemaTypes, err := d.schemaColumnTypes(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		dataType := columnType(schemaTypes, column, payload[column])
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+			pgx.Identifier(table.Parts()).Sanitize(), pgx.Identifier{column}.Sanitize(), dataType)
+
+		_, err := d.pool.Exec(ctx, query)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case sqlStateDuplicateColumn:
+				continue
+			case sqlStateFeatureNotSupported, sqlStateSyntaxError:
+				return fmt.Errorf("add column %q: %w: %w", column, ErrSchemaEvolutionUnsupported, err)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("add column %q: %w", column, err)
+		}
+
+		sdk.Logger(ctx).Info().
+			Str("table", tableName).
+			Str("column", column).
+			Str("type", dataType).
+			Msg("added a column to the table")
+	}
+
+	if _, err := d.columnTypes.Refresh(ctx, tableName); err != nil {
+		return fmt.Errorf("refresh column types: %w", err)
+	}
+
+	return nil
+}
+
+// columnType returns the data type of the column from the schema types,
+// or infers it from the value if the schema lacks the column.
+func columnType(schemaTypes map[string]string, column string, value any) string {
+	if dataType, ok := schemaTypes[column]; ok {
+		return dataType
+	}
+
+	return coltypes.InferType(value)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/jackc/pgconn"
+)
+
+const (
+	// SQLSTATE codes of errors caused by stale column types.
+	// See https://www.postgresql.org/docs/current/errcodes-appendix.html.
+	sqlStateUndefinedColumn  = "42703"
+	sqlStateDatatypeMismatch = "42804"
+)
+
+// convertStructureData converts the data according to the column types of the table.
+func (d *Destination) convertStructureData(
+	ctx context.Context, tableName string, data opencdc.StructuredData,
+) (opencdc.StructuredData, error) {
+	columnTypes, err := d.columnTypes.Get(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("get column types: %w", err)
+	}
+
+	data, err = coltypes.ConvertStructureData(ctx, columnTypes, data)
+	if err != nil {
+		return nil, fmt.Errorf("convert structure data: %w", err)
+	}
+
+	return data, nil
+}
+
+// refreshingColumnTypes wraps the record handler, so that if it fails because the cached column types
+// of the record's table are stale, the column types are refreshed and the handler is called once again.
+func (d *Destination) refreshingColumnTypes(handle recordHandler) recordHandler {
+	return func(ctx context.Context, tableName string, record opencdc.Record) error {
+		err := handle(ctx, tableName, record)
+		if !isStaleColumnTypesError(err) {
+			return err
+		}
+
+		if _, refreshErr := d.columnTypes.Refresh(ctx, tableName); refreshErr != nil {
+			return fmt.Errorf("%w (refresh column types: %w)", err, refreshErr)
+		}
+
+		return handle(ctx, tableName, record)
+	}
+}
+
+// refreshColumnTypes refreshes the column types of the batch's table and prepares the rows
+// of the batch's records once again. The rows may end up with different columns than before,
+// e.g. a field of a column that was dropped from the table is dropped from the rows
+// with the config.UnknownColumnPolicyDrop policy, so they're collected into new batches
+// that can each be written with a single statement, keeping the order of the records.
+func (d *Destination) refreshColumnTypes(ctx context.Context, batch *insertBatch) ([]*insertBatch, error) {
+	if _, err := d.columnTypes.Refresh(ctx, batch.table); err != nil {
+		return nil, fmt.Errorf("refresh column types: %w", err)
+	}
+
+	current := &insertBatch{}
+	batches := []*insertBatch{current}
+	for i, record := range batch.records {
+		row, err := d.prepareInsert(ctx, batch.table, record)
+		if err != nil {
+			return nil, fmt.Errorf("prepare record %d of the batch: %w", i, err)
+		}
+
+		if !current.accepts(row) {
+			current = &insertBatch{}
+			batches = append(batches, current)
+		}
+
+		current.add(batch.indexes[i], record, row)
+	}
+
+	return batches, nil
+}
+
+// isStaleColumnTypesError reports whether the error is caused by a column
+// that doesn't exist or has a type different from the cached one.
+func isStaleColumnTypesError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == sqlStateUndefinedColumn || pgErr.Code == sqlStateDatatypeMismatch
+}
@@ -0,0 +1,87 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"fmt"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/config"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// newPoolConfig builds the config of the connection pool from the connector's config,
+// either from the URL or from the discrete connection parameters.
+func newPoolConfig(cfg config.Config) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.ConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+
+	password, err := cfg.ResolvePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if password != "" {
+		poolConfig.ConnConfig.Password = password
+	}
+
+	poolConfig.MaxConns = cfg.MaxConnections
+
+	if len(cfg.SessionSettings) > 0 {
+		poolConfig.AfterConnect = applySessionSettings(cfg)
+	}
+
+	if err := configureTLS(&poolConfig.ConnConfig.Config, cfg); err != nil {
+		return nil, err
+	}
+
+	return poolConfig, nil
+}
+
+// configureTLS replaces the TLS settings parsed from the URL with the ones of the TLS parameters, if they're set.
+// Every host of the URL is tried with the TLS configs of the mode in turn, the same way as for the sslmode.
+func configureTLS(connConfig *pgconn.Config, cfg config.Config) error {
+	if cfg.TLSMode == "" {
+		return nil
+	}
+
+	// the primary host and the hosts of the fallbacks, which are repeated for every TLS config of the URL's sslmode
+	hosts := []*pgconn.FallbackConfig{{Host: connConfig.Host, Port: connConfig.Port}}
+	for _, fallback := range connConfig.Fallbacks {
+		last := hosts[len(hosts)-1]
+		if fallback.Host != last.Host || fallback.Port != last.Port {
+			hosts = append(hosts, &pgconn.FallbackConfig{Host: fallback.Host, Port: fallback.Port})
+		}
+	}
+
+	var attempts []*pgconn.FallbackConfig
+	for _, host := range hosts {
+		tlsConfigs, err := cfg.TLSConfigs(host.Host)
+		if err != nil {
+			return fmt.Errorf("configure tls: %w", err)
+		}
+
+		for _, tlsConfig := range tlsConfigs {
+			attempts = append(attempts, &pgconn.FallbackConfig{Host: host.Host, Port: host.Port, TLSConfig: tlsConfig})
+		}
+	}
+
+	connConfig.TLSConfig = attempts[0].TLSConfig
+	connConfig.Fallbacks = attempts[1:]
+
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
+	"github.com/jackc/pgx/v4"
+)
+
+// copyNull represents a NULL value in the COPY text format.
+const copyNull = `\N`
+
+// copyEscaper escapes the characters that have a special meaning in the COPY text format.
+var copyEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// execCopy bulk-loads the rows into the table with a single COPY FROM STDIN statement.
+// Every row must contain values for the columns in the same order.
+//
+// Materialize accepts only the text and CSV formats for COPY FROM,
+// so the rows are encoded in the text format instead of the binary one used by pgx.Conn.CopyFrom.
+// The statement isn't safe to run twice, so it's retried only if it certainly wasn't applied, see nonIdempotent.
+func (d *Destination) execCopy(ctx context.Context, tableName string, columns []string, rows [][]any) error {
+	table, err := coltypes.ParseTableName(tableName)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeCopyRows(rows)
+	if err != nil {
+		return fmt.Errorf("encode rows: %w", err)
+	}
+
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = pgx.Identifier{column}.Sanitize()
+	}
+
+	query := fmt.Sprintf("COPY %s (%s) FROM STDIN",
+		pgx.Identifier(table.Parts()).Sanitize(), strings.Join(quotedColumns, ", "))
+
+	// failures to acquire a connection are retried as usual, since nothing has been sent yet
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Conn().PgConn().CopyFrom(ctx, bytes.NewReader(data), query)
+	if err != nil {
+		return fmt.Errorf("failed to exec copy: %w", nonIdempotent(err))
+	}
+
+	return nil
+}
+
+// encodeCopyRows encodes the rows in the COPY text format,
+// a line per row with values separated by tabs.
+func encodeCopyRows(rows [][]any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	for _, row := range rows {
+		for i, value := range row {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+
+			encoded, err := encodeCopyValue(value)
+			if err != nil {
+				return nil, err
+			}
+
+			buf.WriteString(encoded)
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeCopyValue encodes a single value in the COPY text format.
+// The values are expected to be already converted by the coltypes.ConvertStructureData,
+// so the server is able to cast their text representation to the column types.
+func encodeCopyValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return copyNull, nil
+	case string:
+		return copyEscaper.Replace(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []byte:
+		return copyEscaper.Replace(`\x` + hex.EncodeToString(v)), nil
+	case fmt.Stringer:
+		return copyEscaper.Replace(v.String()), nil
+	default:
+		// maps and slices are written as JSON, which is what Materialize expects for jsonb columns.
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("marshal %T value: %w", value, err)
+		}
+
+		return copyEscaper.Replace(string(encoded)), nil
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeCopyRows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		rows    [][]any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "success_scalars",
+			rows: [][]any{
+				{float64(1), "Anon", true, nil},
+				{int64(2), "Alex", false, 1.5},
+			},
+			want: "1\tAnon\ttrue\t\\N\n2\tAlex\tfalse\t1.5\n",
+		},
+		{
+			name: "success_escaped_string",
+			rows: [][]any{
+				{"tab\tnew line\nback\\slash"},
+			},
+			want: "tab\\tnew line\\nback\\\\slash\n",
+		},
+		{
+			name: "success_bytes_and_time",
+			rows: [][]any{
+				{[]byte{0xde, 0xad}, time.Date(2022, 9, 27, 10, 34, 54, 0, time.UTC)},
+			},
+			want: "\\\\xdead\t2022-09-27T10:34:54Z\n",
+		},
+		{
+			name: "success_json",
+			rows: [][]any{
+				{map[string]any{"read": 2}, []any{"a", "b"}},
+			},
+			want: "{\"read\":2}\t[\"a\",\"b\"]\n",
+		},
+		{
+			name: "fail_unsupported_value",
+			rows: [][]any{
+				{make(chan int)},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := encodeCopyRows(tt.rows)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encodeCopyRows() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("encodeCopyRows() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
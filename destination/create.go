@@ -0,0 +1,120 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/jackc/pgx/v4"
+)
+
+// createTableIfMissing creates the table if config.Config.AutoCreateTable is set and the table has no columns.
+//
+// The table gets a column for every field of the payload and the record's key. Data types of the columns are
+// taken from the payload schema attached to the record, or inferred from the values of fields the schema lacks.
+func (d *Destination) createTableIfMissing(
+	ctx context.Context, tableName string, record opencdc.Record, payload opencdc.StructuredData,
+) error {
+	if !d.config.AutoCreateTable {
+		return nil
+	}
+
+	columnTypes, err := d.columnTypes.Get(ctx, tableName)
+	if err != nil {
+		return fmt.Errorf("get column types: %w", err)
+	}
+
+	if len(columnTypes) > 0 {
+		return nil
+	}
+
+	key, err := d.structurizeKey(ctx, record)
+	if err != nil {
+		return fmt.Errorf("failed to get key: %w", err)
+	}
+
+	schemaTypes, err := d.schemaColumnTypes(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	fields := make(opencdc.StructuredData, len(key)+len(payload))
+	for field, value := range key {
+		fields[field] = value
+	}
+
+	for field, value := range payload {
+		fields[field] = value
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+
+	sort.Strings(names)
+
+	columns := make([]string, len(names))
+	for i, name := range names {
+		columns[i] = pgx.Identifier{name}.Sanitize() + " " + columnType(schemaTypes, name, fields[name])
+	}
+
+	table, err := coltypes.ParseTableName(tableName)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)",
+		pgx.Identifier(table.Parts()).Sanitize(), strings.Join(columns, ", "))
+
+	if _, err := d.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("create table %q: %w", tableName, err)
+	}
+
+	sdk.Logger(ctx).Info().Str("table", tableName).Msg("created a missing table")
+
+	if _, err := d.columnTypes.Refresh(ctx, tableName); err != nil {
+		return fmt.Errorf("refresh column types: %w", err)
+	}
+
+	return nil
+}
+
+// schemaColumnTypes returns the column types of the fields of the record's payload schema,
+// with the identifier case applied to their names. It returns nil if the record has no payload schema.
+func (d *Destination) schemaColumnTypes(ctx context.Context, record opencdc.Record) (map[string]string, error) {
+	schema, err := d.payloadSchema(ctx, record)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+
+	schemaTypes, err := coltypes.SchemaColumnTypes(schema)
+	if err != nil {
+		return nil, fmt.Errorf("get payload schema column types: %w", err)
+	}
+
+	columnTypes := make(map[string]string, len(schemaTypes))
+	for field, dataType := range schemaTypes {
+		columnTypes[d.config.IdentifierCase.Apply(field)] = dataType
+	}
+
+	return columnTypes, nil
+}
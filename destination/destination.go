@@ -15,10 +15,15 @@
 package destination
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"text/template"
 
 	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
 	"github.com/conduitio-labs/conduit-connector-materialize/config"
@@ -26,21 +31,26 @@ import (
 	"github.com/conduitio/conduit-commons/opencdc"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/doug-martin/goqu/v9"
-	"github.com/jackc/pgx/v4"
-)
-
-const (
-	// metadata related.
-	metadataTable = "materialize.table"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/hamba/avro/v2"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // Destination Materialize Connector persists records to an Materialize database.
 type Destination struct {
 	sdk.UnimplementedDestination
 
-	conn        *pgx.Conn
-	columnTypes map[string]string
+	// pool is the pool of connections shared by the writers of different tables.
+	pool *pgxpool.Pool
+	// columnTypes caches column types of every table the destination writes to.
+	columnTypes *coltypes.Cache
 	config      config.Config
+	// tableTemplate renders table names of records, it's nil if the configured table is a plain table name.
+	tableTemplate *template.Template
+	// schemas caches key and payload schemas of records.
+	schemas schemaCache
+	// secrets are the credentials of the connection, which are redacted from error messages.
+	secrets []string
 }
 
 // NewDestination creates new instance of the Destination.
@@ -52,130 +62,451 @@ func NewDestination() sdk.Destination {
 func (d *Destination) Parameters() cconfig.Parameters {
 	return map[string]cconfig.Parameter{
 		config.KeyURL: {
+			Default: "",
+			Description: "The connection URL for Materialize instance. " +
+				"Either the url or the host must be set, the url can't be combined with the other connection parameters.",
+		},
+		config.KeyHost: {
 			Default:     "",
-			Description: "The connection URL for Materialize instance.",
-			Validations: []cconfig.Validation{cconfig.ValidationRequired{}},
+			Description: "The host of Materialize instance, used instead of the url.",
 		},
-		config.KeyTable: {
+		config.KeyPort: {
+			Default:     "",
+			Description: "The port of Materialize instance, used along with the host. Defaults to 6875.",
+			Type:        cconfig.ParameterTypeInt,
+			Validations: []cconfig.Validation{
+				cconfig.ValidationGreaterThan{V: 0},
+				cconfig.ValidationLessThan{V: 65536},
+			},
+		},
+		config.KeyUser: {
+			Default:     "",
+			Description: "The user to connect as, used along with the host.",
+		},
+		config.KeyPassword: {
+			Default:     "",
+			Description: "The password of the user, used along with the host. It can't be combined with passwordFile.",
+		},
+		config.KeyPasswordFile: {
+			Default:     "",
+			Description: "The path to a file containing the password of the user, used along with the host.",
+		},
+		config.KeyDatabase: {
 			Default:     "",
-			Description: "The table name of the table in Materialize that the connector should write to, by default.",
+			Description: "The database to connect to, used along with the host. Defaults to \"materialize\".",
+		},
+		config.KeyOptions: {
+			Default: "",
+			Description: "The command-line options sent to Materialize at connection start, used along with the host, " +
+				"e.g. \"--cluster=quickstart\".",
+		},
+		config.KeyTable: {
+			Default: "",
+			Description: "The table name of the table in Materialize that the connector should write to, by default. " +
+				"It can also be a Go template evaluated against the record's Metadata, Key and Payload.",
 			Validations: []cconfig.Validation{cconfig.ValidationRequired{}},
 		},
 		config.KeyKey: {
 			Default:     "",
-			Description: "The column name used when updating and deleting records.",
+			Description: "Comma-separated list of the key column names used when updating and deleting records.",
 			Validations: []cconfig.Validation{cconfig.ValidationRequired{}},
 		},
+		config.KeyUpdateMode: {
+			Default: string(config.UpdateModeUpdate),
+			Description: "The mode of updates. " +
+				"Use \"upsert\" to insert records if an update doesn't match any rows.",
+			Validations: []cconfig.Validation{cconfig.ValidationInclusion{
+				List: []string{string(config.UpdateModeUpdate), string(config.UpdateModeUpsert)},
+			}},
+		},
+		config.KeyTableUpdateModes: {
+			Default:     "",
+			Description: "Comma-separated list of table:mode pairs that override the update mode for specific tables.",
+		},
+		config.KeyNestedMode: {
+			Default: string(config.NestedModeStringify),
+			Description: "The mode of writing nested objects. Use \"stringify\" to write them to a single column, " +
+				"encoded according to the column type, or \"flatten\" to expand them into columns.",
+			Validations: []cconfig.Validation{cconfig.ValidationInclusion{
+				List: []string{string(config.NestedModeStringify), string(config.NestedModeFlatten)},
+			}},
+		},
+		config.KeyFlattenSeparator: {
+			Default:     "_",
+			Description: "The separator between the names of a nested object and its fields in flattened column names.",
+		},
+		config.KeyFlattenMaxDepth: {
+			Default: "0",
+			Description: "The maximum number of nesting levels expanded into columns, " +
+				"objects nested deeper are written to a single column. Zero means there is no limit.",
+			Validations: []cconfig.Validation{cconfig.ValidationGreaterThan{V: -1}},
+		},
+		config.KeyIdentifierCase: {
+			Default: string(config.IdentifierCaseLower),
+			Description: "The case of table, key and column names. " +
+				"Use \"lower\" to fold them to lower case, or \"preserve\" to keep them exactly as they are.",
+			Validations: []cconfig.Validation{cconfig.ValidationInclusion{
+				List: []string{string(config.IdentifierCaseLower), string(config.IdentifierCasePreserve)},
+			}},
+		},
+		config.KeyRoutingMetadataKeys: {
+			Default: config.MetadataTable,
+			Description: "Comma-separated list of record metadata keys that contain the table name, " +
+				"in the order of precedence. Supported keys are \"materialize.table\" and \"opencdc.collection\".",
+		},
+		config.KeyCollectionPrefix: {
+			Default:     "",
+			Description: "The prefix added to table names taken from the \"opencdc.collection\" metadata key.",
+		},
+		config.KeyCollectionSuffix: {
+			Default:     "",
+			Description: "The suffix added to table names taken from the \"opencdc.collection\" metadata key.",
+		},
+		config.KeyCollectionMapping: {
+			Default: "",
+			Description: "Comma-separated list of collection:table pairs that map collections to table names. " +
+				"Mapped table names are used without the collection prefix and suffix.",
+		},
+		config.KeyAutoCreateTable: {
+			Default: "false",
+			Description: "Whether to create missing tables with column types inferred from the payload schema " +
+				"attached to the record, or from the payload values if there is no schema.",
+			Type: cconfig.ParameterTypeBool,
+		},
+		config.KeyUnknownColumns: {
+			Default: string(config.UnknownColumnPolicyFail),
+			Description: "The policy for payload fields that don't exist in the target table. " +
+				"Use \"fail\" to fail the record, \"drop\" to drop the fields and log them, " +
+				"or \"add\" to add the columns to the table.",
+			Validations: []cconfig.Validation{cconfig.ValidationInclusion{
+				List: []string{
+					string(config.UnknownColumnPolicyFail),
+					string(config.UnknownColumnPolicyDrop),
+					string(config.UnknownColumnPolicyAdd),
+				},
+			}},
+		},
+		config.KeyMaxConnections: {
+			Default: "4",
+			Description: "The maximum number of connections to Materialize, " +
+				"which is also the maximum number of tables written concurrently.",
+			Type:        cconfig.ParameterTypeInt,
+			Validations: []cconfig.Validation{cconfig.ValidationGreaterThan{V: 0}},
+		},
+		config.KeyMaxRetries: {
+			Default: "3",
+			Description: "The maximum number of retries of a statement that fails with a transient error, " +
+				"e.g. because the connection dropped. Zero disables retries.",
+			Type:        cconfig.ParameterTypeInt,
+			Validations: []cconfig.Validation{cconfig.ValidationGreaterThan{V: -1}},
+		},
+		config.KeyRetryBackoff: {
+			Default:     "1s",
+			Description: "The backoff before the first retry, it's doubled before every next retry up to 30 seconds.",
+			Type:        cconfig.ParameterTypeDuration,
+		},
+		config.KeyTLSMode: {
+			Default: "",
+			Description: "The TLS mode, one of \"disable\", \"allow\", \"prefer\", \"require\", \"verify-ca\" " +
+				"or \"verify-full\". It overrides the sslmode of the url, which applies if it's not set.",
+			Validations: []cconfig.Validation{cconfig.ValidationInclusion{
+				List: []string{
+					string(config.TLSModeDisable),
+					string(config.TLSModeAllow),
+					string(config.TLSModePrefer),
+					string(config.TLSModeRequire),
+					string(config.TLSModeVerifyCA),
+					string(config.TLSModeVerifyFull),
+				},
+			}},
+		},
+		config.KeyTLSCACert: {
+			Default:     "",
+			Description: "The CA certificate used to verify the server certificate, either inline PEM or a path to a PEM file.",
+		},
+		config.KeyTLSClientCert: {
+			Default:     "",
+			Description: "The client certificate for mutual TLS, either inline PEM or a path to a PEM file.",
+		},
+		config.KeyTLSClientKey: {
+			Default:     "",
+			Description: "The key of the client certificate, either inline PEM or a path to a PEM file.",
+		},
+		config.KeyTLSServerName: {
+			Default:     "",
+			Description: "The server name used for SNI and to verify the server certificate. Defaults to the host.",
+		},
+		config.KeySessionSettings + ".*": {
+			Default: "",
+			Description: "The session settings applied to every connection, e.g. sessionSettings.cluster, " +
+				"sessionSettings.search_path or sessionSettings.statement_timeout. " +
+				"The connector fails to start if a setting doesn't exist or its value is invalid.",
+		},
+		config.KeyWriteMode: {
+			Default: string(config.WriteModeAppend),
+			Description: "The mode of writes. " +
+				"Use \"replace\" to delete the rows matching the key before inserting a record, " +
+				"so that there is exactly one row per key.",
+			Validations: []cconfig.Validation{cconfig.ValidationInclusion{
+				List: []string{string(config.WriteModeAppend), string(config.WriteModeReplace)},
+			}},
+		},
 	}
 }
 
 // Configure parses and initializes the config.
 func (d *Destination) Configure(_ context.Context, cfg cconfig.Config) error {
+	d.secrets = config.Secrets(cfg)
+
 	configuration, err := config.Parse(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return redact(fmt.Errorf("failed to parse config: %w", err), d.secrets)
 	}
 
 	d.config = configuration
 
+	d.tableTemplate, err = configuration.TableTemplate()
+	if err != nil {
+		return fmt.Errorf("failed to parse table template: %w", err)
+	}
+
 	return nil
 }
 
 // Open makes sure everything is prepared to receive records.
 func (d *Destination) Open(ctx context.Context) error {
-	conn, err := pgx.Connect(ctx, d.config.URL)
+	return redact(d.open(ctx), d.secrets)
+}
+
+// open connects to Materialize, validates the session settings and fetches the column types of the configured table.
+func (d *Destination) open(ctx context.Context) error {
+	poolConfig, err := newPoolConfig(d.config)
+	if err != nil {
+		return err
+	}
+
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
 	if err != nil {
 		return fmt.Errorf("failed to connect to materialize: %w", err)
 	}
 
-	d.conn = conn
+	d.pool = pool
+	d.columnTypes = coltypes.NewCache(d.pool)
 
-	d.columnTypes, err = coltypes.GetColumnTypes(ctx, d.conn, d.config.Table)
-	if err != nil {
-		return fmt.Errorf("get column types: %w", err)
+	if err = d.validateSessionSettings(ctx); err != nil {
+		return err
+	}
+
+	// column types of other tables are fetched when the first record routed to them arrives,
+	// as well as the table names rendered by a template
+	if d.tableTemplate == nil {
+		if _, err = d.columnTypes.Get(ctx, d.config.Table); err != nil {
+			return fmt.Errorf("get column types: %w", err)
+		}
 	}
 
 	return nil
 }
 
-// Write writes a record into a Destination.
+// Write writes records into a Destination.
+//
+// Records are grouped by their tables, and the groups are written concurrently
+// using connections from the pool. Records of the same table are written one after another
+// in the order they're received, so the order of records with the same key is kept.
+// See Destination.writeTable for the way records of a table are written.
+//
+// If a record fails, Write returns the number of records preceding it along with its error.
+// All the preceding records are written, and none of the following records is inserted.
+// Updates and deletes following the failed record may have been written by the writers of other tables,
+// but writing them once again when the records are redelivered has no effect.
 func (d *Destination) Write(ctx context.Context, records []opencdc.Record) (int, error) {
-	for i, record := range records {
-		err := sdk.Util.Destination.Route(ctx, record,
-			d.insert,
-			d.update,
-			d.delete,
-			d.insert,
-		)
+	progress := newWriteProgress()
+
+	tables := d.groupByTable(records, progress)
+
+	var wg sync.WaitGroup
+	for _, table := range tables {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			d.writeTable(ctx, table, progress)
+		}()
+	}
+
+	wg.Wait()
+
+	if progress.err != nil {
+		return progress.index, redact(progress.err, d.secrets)
+	}
+
+	return len(records), nil
+}
+
+// flush writes all the rows collected in the batch with a single INSERT or COPY statement and resets the batch.
+// Both statements either write all rows or none of them, so if it fails
+// none of the records starting from batch.start are written.
+//
+// If the statement fails because the cached column types of the table are stale,
+// the column types are refreshed and the rows are prepared once again, possibly splitting them
+// into several batches, which are written one after another, see Destination.refreshColumnTypes.
+// If one of them fails, the records of the preceding ones are written.
+// Statements that fail with transient errors are retried, see Destination.retry.
+//
+// It returns the index of the first record that wasn't written along with the error.
+func (d *Destination) flush(ctx context.Context, batch *insertBatch) (int, error) {
+	if len(batch.rows) == 0 {
+		return 0, nil
+	}
+
+	defer batch.reset()
+
+	// the column types are refreshed at most once, so that errors the refresh doesn't fix are reported
+	pending, refreshed := []*insertBatch{batch}, false
+	for len(pending) > 0 {
+		current := pending[0]
+
+		var rebuilt []*insertBatch
+		err := d.retry(ctx, current.table, func() error {
+			err := d.execBatch(ctx, current)
+			if refreshed || !isStaleColumnTypesError(err) {
+				return err
+			}
+
+			rebuilt, err = d.refreshColumnTypes(ctx, current)
+
+			return err
+		})
 		if err != nil {
-			return i, fmt.Errorf("route %s: %w", record.Operation.String(), err)
+			return current.start, fmt.Errorf("write batch of %d records: %w", len(current.rows), err)
 		}
+
+		if rebuilt != nil {
+			pending, refreshed = rebuilt, true
+
+			continue
+		}
+
+		pending = pending[1:]
 	}
 
-	return len(records), nil
+	return 0, nil
+}
+
+// execBatch writes the rows of the batch with a single COPY statement for snapshots, or INSERT statement otherwise.
+func (d *Destination) execBatch(ctx context.Context, batch *insertBatch) error {
+	if batch.snapshot {
+		return d.execCopy(ctx, batch.table, batch.columns, batch.rows)
+	}
+
+	return d.execInsert(ctx, batch.table, batch.columns, batch.rows)
 }
 
 // insert is an append-only operation that doesn't care about keys.
-func (d *Destination) insert(ctx context.Context, record opencdc.Record) error {
-	tableName := d.getTableName(record.Metadata)
+func (d *Destination) insert(ctx context.Context, tableName string, record opencdc.Record) error {
+	row, err := d.prepareInsert(ctx, tableName, record)
+	if err != nil {
+		return err
+	}
 
-	payload, err := d.structurizeData(record.Payload.After)
+	return d.execInsert(ctx, row.table, row.columns, [][]any{row.values})
+}
+
+// prepareInsert converts the record's payload into a row ready to be inserted.
+func (d *Destination) prepareInsert(ctx context.Context, tableName string, record opencdc.Record) (insertRow, error) {
+	payload, err := d.structurizePayload(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to get payload: %w", err)
+		return insertRow{}, fmt.Errorf("failed to get payload: %w", err)
 	}
 
 	// if payload is empty we don't need to insert anything
 	if payload == nil {
-		return ErrEmptyPayload
+		return insertRow{}, ErrEmptyPayload
 	}
 
-	payload, err = coltypes.ConvertStructureData(ctx, d.columnTypes, payload)
+	payload, err = d.convertPayload(ctx, tableName, record, payload)
 	if err != nil {
-		return fmt.Errorf("convert structure data: %w", err)
+		return insertRow{}, err
 	}
 
-	colArgs, valArgs := d.extractColumnsAndValues(payload)
+	columns, values := d.extractColumnsAndValues(payload)
 
-	query, args, err := goqu.
-		Insert(tableName).
-		Cols(colArgs...).
-		Vals(valArgs).
-		ToSQL()
+	return insertRow{
+		table:    tableName,
+		columns:  columns,
+		values:   values,
+		snapshot: record.Operation == opencdc.OperationSnapshot,
+	}, nil
+}
+
+// execInsert inserts the rows into the table with a single INSERT statement.
+// Every row must contain values for the columns in the same order.
+// The statement isn't safe to run twice, so it's retried only if it certainly wasn't applied, see nonIdempotent.
+func (d *Destination) execInsert(ctx context.Context, tableName string, columns []string, rows [][]any) error {
+	query, args, err := insertQuery(tableName, columns, rows)
 	if err != nil {
-		return fmt.Errorf("error formating query: %w", err)
+		return err
+	}
+
+	// failures to acquire a connection are retried as usual, since nothing has been sent yet
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
 	}
+	defer conn.Release()
 
-	_, err = d.conn.Exec(ctx, query, args...)
+	_, err = conn.Exec(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to exec insert: %w", err)
+		return fmt.Errorf("failed to exec insert: %w", nonIdempotent(err))
 	}
 
 	return nil
 }
 
+// insertQuery builds an INSERT statement of the rows into the table.
+func insertQuery(tableName string, columns []string, rows [][]any) (string, []any, error) {
+	table, err := tableIdentifier(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	colArgs := make([]any, len(columns))
+	for i, column := range columns {
+		colArgs[i] = column
+	}
+
+	query, args, err := goqu.
+		Insert(table).
+		Cols(colArgs...).
+		Vals(rows...).
+		ToSQL()
+	if err != nil {
+		return "", nil, fmt.Errorf("error formating query: %w", err)
+	}
+
+	return query, args, nil
+}
+
 // update updates records by a key.
 //
 // Note that Materialize doesn't support primary keys and unique constraints,
 // so if there are duplicate keys in Materialize the connector will update all of them.
-func (d *Destination) update(ctx context.Context, record opencdc.Record) error {
-	tableName := d.getTableName(record.Metadata)
-
-	key, err := d.structurizeData(record.Key)
+//
+// If the update mode of the table is config.UpdateModeUpsert and there are no rows matching the key,
+// the record is inserted instead.
+func (d *Destination) update(ctx context.Context, tableName string, record opencdc.Record) error {
+	key, err := d.structurizeKey(ctx, record)
 	if err != nil {
 		return fmt.Errorf("failed to get key: %w", err)
 	}
 
-	keyColumnName, err := d.getKeyColumnName(key)
+	condition, key, err := d.keyCondition(ctx, tableName, key)
 	if err != nil {
-		return fmt.Errorf("failed to get key column name: %w", err)
-	}
-
-	// do nothing if we didn't find a value for the key
-	if _, ok := key[keyColumnName]; !ok {
-		return ErrEmptyKey
+		return err
 	}
 
-	payload, err := d.structurizeData(record.Payload.After)
+	payload, err := d.structurizePayload(ctx, record)
 	if err != nil {
 		return fmt.Errorf("failed to get payload: %w", err)
 	}
@@ -185,28 +516,50 @@ func (d *Destination) update(ctx context.Context, record opencdc.Record) error {
 		return ErrEmptyPayload
 	}
 
-	payload, err = coltypes.ConvertStructureData(ctx, d.columnTypes, payload)
+	payload, err = d.convertPayload(ctx, tableName, record, payload)
 	if err != nil {
-		return fmt.Errorf("convert structure data: %w", err)
+		return err
 	}
 
 	// remove key from the payload, we will use the key inside a WHERE clause.
-	delete(payload, keyColumnName)
+	for keyColumn := range key {
+		delete(payload, keyColumn)
+	}
+
+	table, err := tableIdentifier(tableName)
+	if err != nil {
+		return err
+	}
 
 	query, args, err := goqu.
-		Update(tableName).
+		Update(table).
 		Set(payload).
-		Where(goqu.Ex{keyColumnName: key[keyColumnName]}).
+		Where(condition).
 		ToSQL()
 	if err != nil {
 		return fmt.Errorf("error formating query: %w", err)
 	}
 
-	_, err = d.conn.Exec(ctx, query, args...)
+	commandTag, err := d.pool.Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to exec update: %w", err)
 	}
 
+	if commandTag.RowsAffected() > 0 || d.config.UpdateModeOf(tableName) != config.UpdateModeUpsert {
+		return nil
+	}
+
+	// there is nothing to update, so insert the payload along with the key
+	for keyColumn, value := range key {
+		payload[keyColumn] = value
+	}
+
+	columns, values := d.extractColumnsAndValues(payload)
+
+	if err := d.execInsert(ctx, tableName, columns, [][]any{values}); err != nil {
+		return fmt.Errorf("upsert: %w", err)
+	}
+
 	return nil
 }
 
@@ -215,33 +568,31 @@ func (d *Destination) update(ctx context.Context, record opencdc.Record) error {
 //
 // Note that Materialize doesn't support primary keys and unique constraints,
 // so if there are duplicate keys in Materialize the connector will delete them all.
-func (d *Destination) delete(ctx context.Context, record opencdc.Record) error {
-	tableName := d.getTableName(record.Metadata)
-
-	key, err := d.structurizeData(record.Key)
+func (d *Destination) delete(ctx context.Context, tableName string, record opencdc.Record) error {
+	key, err := d.structurizeKey(ctx, record)
 	if err != nil {
 		return fmt.Errorf("failed to get key: %w", err)
 	}
 
-	keyColumnName, err := d.getKeyColumnName(key)
+	condition, _, err := d.keyCondition(ctx, tableName, key)
 	if err != nil {
-		return fmt.Errorf("failed to get key column name: %w", err)
+		return err
 	}
 
-	// do nothing if we didn't find a value for the key
-	if _, ok := key[keyColumnName]; !ok {
-		return ErrEmptyKey
+	table, err := tableIdentifier(tableName)
+	if err != nil {
+		return err
 	}
 
 	query, args, err := goqu.
-		Delete(tableName).
-		Where(goqu.Ex{keyColumnName: key[keyColumnName]}).
+		Delete(table).
+		Where(condition).
 		ToSQL()
 	if err != nil {
 		return fmt.Errorf("error formating query: %w", err)
 	}
 
-	_, err = d.conn.Exec(ctx, query, args...)
+	_, err = d.pool.Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to exec delete: %w", err)
 	}
@@ -249,79 +600,188 @@ func (d *Destination) delete(ctx context.Context, record opencdc.Record) error {
 	return nil
 }
 
+// keyCondition builds a condition matching the rows by all the key columns of the key.
+// It returns the condition and the converted values of the key columns.
+func (d *Destination) keyCondition(
+	ctx context.Context, tableName string, key opencdc.StructuredData,
+) (exp.ExpressionList, opencdc.StructuredData, error) {
+	keyColumns := d.getKeyColumnNames(key)
+
+	// do nothing if we didn't find a value for each of the key columns
+	for _, keyColumn := range keyColumns {
+		if _, ok := key[keyColumn]; !ok {
+			return nil, nil, ErrEmptyKey
+		}
+	}
+
+	key, err := d.convertStructureData(ctx, tableName, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key: %w", err)
+	}
+
+	conditions := make([]exp.Expression, len(keyColumns))
+	for i, keyColumn := range keyColumns {
+		conditions[i] = goqu.C(keyColumn).Eq(key[keyColumn])
+	}
+
+	return goqu.And(conditions...), key, nil
+}
+
 // extractColumnsAndValues turns the payload into slices of
 // columns and values for upserting into Materialize.
-func (d *Destination) extractColumnsAndValues(payload opencdc.StructuredData) ([]any, []any) {
-	var colArgs, valArgs []any
+// Columns are sorted, so payloads with the same set of fields produce the same columns.
+func (d *Destination) extractColumnsAndValues(payload opencdc.StructuredData) ([]string, []any) {
+	columns := make([]string, 0, len(payload))
+	for field := range payload {
+		columns = append(columns, field)
+	}
+
+	sort.Strings(columns)
+
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = payload[column]
+	}
+
+	return columns, values
+}
+
+// structurizePayload structurizes the record's payload according to its payload schema, if there is one.
+func (d *Destination) structurizePayload(ctx context.Context, record opencdc.Record) (opencdc.StructuredData, error) {
+	schema, err := d.payloadSchema(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.structurizeData(record.Payload.After, schema)
+}
+
+// structurizeKey structurizes the record's key according to its key schema, if there is one.
+func (d *Destination) structurizeKey(ctx context.Context, record opencdc.Record) (opencdc.StructuredData, error) {
+	schema, err := d.keySchema(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.structurizeData(record.Key, schema)
+}
+
+// structurizeData converts opencdc.Data to opencdc.StructuredData
+// with field names and nested objects prepared to be written into columns.
+// Values are converted according to the schema first, unless it's nil.
+func (d *Destination) structurizeData(data opencdc.Data, schema avro.Schema) (opencdc.StructuredData, error) {
+	structuredData, err := decodeData(data)
+	if err != nil || structuredData == nil {
+		return nil, err
+	}
+
+	if schema != nil {
+		structuredData, err = coltypes.ConvertSchemaData(schema, structuredData)
+		if err != nil {
+			return nil, fmt.Errorf("convert data according to schema: %w", err)
+		}
+	}
 
-	for field, value := range payload {
-		colArgs = append(colArgs, field)
-		valArgs = append(valArgs, value)
+	// apply the identifier case to the field names
+	result := make(opencdc.StructuredData, len(structuredData))
+	paths := make(map[string]string, len(structuredData))
+	for _, key := range slices.Sorted(maps.Keys(structuredData)) {
+		err := d.setField(result, paths, d.config.IdentifierCase.Apply(key), key, structuredData[key], 1)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return colArgs, valArgs
+	return result, nil
 }
 
-// structurizeData converts opencdc.Data to opencdc.StructuredData.
-func (d *Destination) structurizeData(data opencdc.Data) (opencdc.StructuredData, error) {
+// decodeData decodes opencdc.Data into opencdc.StructuredData as is.
+// Numbers are decoded as json.Number values, so that they keep their exact values
+// until they're converted to the types of the columns they're written to.
+func decodeData(data opencdc.Data) (opencdc.StructuredData, error) {
 	if data == nil || len(data.Bytes()) == 0 {
 		return nil, nil
 	}
 
+	decoder := json.NewDecoder(bytes.NewReader(data.Bytes()))
+	decoder.UseNumber()
+
 	structuredData := make(opencdc.StructuredData)
-	err := json.Unmarshal(data.Bytes(), &structuredData)
+	err := decoder.Decode(&structuredData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal data into structured data: %w", err)
 	}
 
-	structuredDataLower := make(opencdc.StructuredData)
-	for key, value := range structuredData {
-		if parsedValue, ok := value.(map[string]any); ok {
-			valueJSON, err := json.Marshal(parsedValue)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal map into json: %w", err)
-			}
-
-			structuredDataLower[strings.ToLower(key)] = string(valueJSON)
+	return structuredData, nil
+}
 
-			continue
+// setField sets the value of the field to the structured data.
+// A nested object is either expanded into fields named after its path, in the config.NestedModeFlatten mode,
+// or written to a single column as is.
+// The path is the path of the field within the original data, the paths of the fields set so far are kept
+// by their names, so that fields that end up with the same name, e.g. "a_b" and "a.b" flattened with
+// the "_" separator, fail with a *columnCollisionError instead of overwriting one another.
+// The depth is the nesting level of the value, starting from 1 for the top level.
+func (d *Destination) setField(
+	data opencdc.StructuredData, paths map[string]string, name, path string, value any, depth int,
+) error {
+	parsedValue, ok := value.(map[string]any)
+	if ok && d.config.NestedMode == config.NestedModeFlatten &&
+		(d.config.FlattenMaxDepth == 0 || depth <= d.config.FlattenMaxDepth) {
+		for _, key := range slices.Sorted(maps.Keys(parsedValue)) {
+			nestedName := name + d.config.FlattenSeparator + d.config.IdentifierCase.Apply(key)
+			if err := d.setField(data, paths, nestedName, path+"."+key, parsedValue[key], depth+1); err != nil {
+				return err
+			}
 		}
 
-		structuredDataLower[strings.ToLower(key)] = value
+		return nil
 	}
 
-	return structuredDataLower, nil
-}
-
-// getTableName returns either the records metadata value for table
-// or the default configured value for table.
-func (d *Destination) getTableName(metadata map[string]string) string {
-	tableName, ok := metadata[metadataTable]
-	if !ok {
-		return d.config.Table
+	if existing, ok := paths[name]; ok {
+		return &columnCollisionError{Column: name, Paths: [2]string{existing, path}}
 	}
 
-	return strings.ToLower(tableName)
+	paths[name] = path
+
+	// an object is encoded once the type of its column is known, see coltypes.ConvertStructureData
+	data[name] = value
+
+	return nil
 }
 
-// getKeyColumnName returns either the first key within the Key structured data
-// or the default key configured value for key.
-func (d *Destination) getKeyColumnName(key opencdc.StructuredData) (string, error) {
-	if len(key) > 1 {
-		return "", ErrCompositeKeysNotSupported
+// getKeyColumnNames returns either the fields of the Key structured data
+// or the default configured value for key.
+// The fields of the Key are ordered as the configured key columns,
+// fields that are not configured as key columns come last in alphabetical order.
+func (d *Destination) getKeyColumnNames(key opencdc.StructuredData) []string {
+	if len(key) == 0 {
+		return d.config.Key
 	}
 
-	for k := range key {
-		return k, nil
+	keyColumns := make([]string, 0, len(key))
+	for _, keyColumn := range d.config.Key {
+		if _, ok := key[keyColumn]; ok {
+			keyColumns = append(keyColumns, keyColumn)
+		}
 	}
 
-	return d.config.Key, nil
+	var rest []string
+	for field := range key {
+		if !slices.Contains(keyColumns, field) {
+			rest = append(rest, field)
+		}
+	}
+
+	sort.Strings(rest)
+
+	return append(keyColumns, rest...)
 }
 
 // Teardown gracefully closes connections.
-func (d *Destination) Teardown(ctx context.Context) error {
-	if d.conn != nil {
-		return d.conn.Close(ctx)
+func (d *Destination) Teardown(context.Context) error {
+	if d.pool != nil {
+		d.pool.Close()
 	}
 
 	return nil
@@ -14,13 +14,137 @@
 
 package destination
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
+	"github.com/conduitio/conduit-commons/opencdc"
+)
 
 var (
 	// ErrEmptyPayload occurs when a provided payload is empty.
 	ErrEmptyPayload = errors.New("payload cannot be empty")
 	// ErrEmptyKey occurs when there is no value for key.
 	ErrEmptyKey = errors.New("key value must be provided")
-	// ErrCompositeKeysNotSupported occurs when there are more than one key in a Key map.
-	ErrCompositeKeysNotSupported = errors.New("composite keys not yet supported")
+	// ErrEmptyTableName occurs when there is no table name for a record.
+	ErrEmptyTableName = errors.New("table name cannot be empty")
+	// ErrUnknownColumns occurs when a payload contains fields that don't exist in the table.
+	ErrUnknownColumns = errors.New("payload contains fields that don't exist in the table")
+	// ErrReplaceIncomplete occurs when a replace deleted the rows matching the key, but failed to insert the new row,
+	// so the key has no rows until the record is written again.
+	ErrReplaceIncomplete = errors.New("the old rows of the key were deleted, but the new row wasn't inserted")
+	// ErrColumnCollision occurs when several fields of a record are written to the same column.
+	ErrColumnCollision = errors.New("fields are written to the same column")
+	// ErrSchemaEvolutionUnsupported occurs when Materialize rejects adding a column to a table.
+	ErrSchemaEvolutionUnsupported = errors.New("materialize doesn't support adding columns to tables, " +
+		"add the column manually or use the \"drop\" or \"fail\" unknown column policy")
 )
+
+// FieldError occurs when a value of a record's field can't be converted to the type of its column,
+// e.g. when an integer is out of the column's range, or when several fields are written to the same column.
+type FieldError struct {
+	// Position is the position of the record.
+	Position opencdc.Position
+	// Field is the name of the field.
+	Field string
+	// Type is the data type of the column.
+	Type string
+	// Err is the reason of the failure.
+	Err error
+}
+
+// Error returns the error message naming the record's position.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("record at position %s: %s", e.Position, e.Err)
+}
+
+// Unwrap returns the reason of the failure.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// columnCollisionError occurs when two fields of a record end up with the same column name,
+// e.g. after flattening nested objects or applying the identifier case.
+type columnCollisionError struct {
+	// Column is the name of the column.
+	Column string
+	// Paths are the paths of the fields within the record, nested field names are joined with dots.
+	Paths [2]string
+}
+
+// Error returns the error message naming both fields.
+func (e *columnCollisionError) Error() string {
+	return fmt.Sprintf("%s: fields %q and %q are both written to the column %q",
+		ErrColumnCollision, e.Paths[0], e.Paths[1], e.Column)
+}
+
+// Unwrap returns ErrColumnCollision.
+func (e *columnCollisionError) Unwrap() error {
+	return ErrColumnCollision
+}
+
+// recordError returns a *FieldError naming the record's position if the error is caused
+// by a field that can't be converted or that collides with another field, otherwise it returns the error as is.
+func recordError(record opencdc.Record, err error) error {
+	var collisionErr *columnCollisionError
+	if errors.As(err, &collisionErr) {
+		return &FieldError{
+			Position: record.Position,
+			Field:    collisionErr.Column,
+			Err:      err,
+		}
+	}
+
+	var conversionErr *coltypes.ConversionError
+	if !errors.As(err, &conversionErr) {
+		return err
+	}
+
+	return &FieldError{
+		Position: record.Position,
+		Field:    conversionErr.Field,
+		Type:     conversionErr.Type,
+		Err:      err,
+	}
+}
+
+// redactedPlaceholder replaces credentials in error messages.
+const redactedPlaceholder = "xxxxx"
+
+// redactedError is an error whose message has the credentials of the connection replaced.
+// It still unwraps to the original error, so that errors.Is and errors.As work as usual.
+type redactedError struct {
+	err     error
+	message string
+}
+
+// Error returns the redacted error message.
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the original error.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redact replaces the secrets in the error message with a placeholder.
+// It returns the error as is if the message doesn't contain any of them.
+func redact(err error, secrets []string) error {
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	for _, secret := range secrets {
+		message = strings.ReplaceAll(message, secret, redactedPlaceholder)
+	}
+
+	if message == err.Error() {
+		return err
+	}
+
+	return &redactedError{err: err, message: message}
+}
@@ -0,0 +1,102 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/doug-martin/goqu/v9"
+)
+
+// replace deletes all the rows matching the record's key and inserts the record's payload.
+// It's used for creates, snapshots and updates in the config.WriteModeReplace mode,
+// so every key maps to exactly one row no matter how many times a record is written.
+//
+// Materialize doesn't allow DELETE within explicit transactions, so the statements run one after another
+// and aren't atomic: if the INSERT fails, the key has no rows until the record is written again,
+// which is reported with ErrReplaceIncomplete. Writing the record again is safe, since it deletes the rows first.
+//
+// If the record has no key, the values of the configured key columns are taken from the payload.
+func (d *Destination) replace(ctx context.Context, tableName string, record opencdc.Record) error {
+	payload, err := d.structurizePayload(ctx, record)
+	if err != nil {
+		return fmt.Errorf("failed to get payload: %w", err)
+	}
+
+	// if payload is empty we don't need to insert anything
+	if payload == nil {
+		return ErrEmptyPayload
+	}
+
+	key, err := d.structurizeKey(ctx, record)
+	if err != nil {
+		return fmt.Errorf("failed to get key: %w", err)
+	}
+
+	if len(key) == 0 {
+		key = make(opencdc.StructuredData, len(d.config.Key))
+		for _, keyColumn := range d.config.Key {
+			if value, ok := payload[keyColumn]; ok {
+				key[keyColumn] = value
+			}
+		}
+	}
+
+	condition, key, err := d.keyCondition(ctx, tableName, key)
+	if err != nil {
+		return err
+	}
+
+	payload, err = d.convertPayload(ctx, tableName, record, payload)
+	if err != nil {
+		return err
+	}
+
+	for keyColumn, value := range key {
+		payload[keyColumn] = value
+	}
+
+	table, err := tableIdentifier(tableName)
+	if err != nil {
+		return err
+	}
+
+	deleteSQL, deleteArgs, err := goqu.
+		Delete(table).
+		Where(condition).
+		ToSQL()
+	if err != nil {
+		return fmt.Errorf("error formating query: %w", err)
+	}
+
+	columns, values := d.extractColumnsAndValues(payload)
+
+	insertSQL, insertArgs, err := insertQuery(tableName, columns, [][]any{values})
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.pool.Exec(ctx, deleteSQL, deleteArgs...); err != nil {
+		return fmt.Errorf("replace: failed to exec delete: %w", err)
+	}
+
+	if _, err := d.pool.Exec(ctx, insertSQL, insertArgs...); err != nil {
+		return fmt.Errorf("replace: %w: failed to exec insert: %w", ErrReplaceIncomplete, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/jackc/pgconn"
+)
+
+const (
+	// maxRetryBackoff caps the exponential backoff between retries.
+	maxRetryBackoff = 30 * time.Second
+
+	// SQLSTATE class of connection exceptions.
+	sqlStateClassConnectionException = "08"
+	// SQLSTATE codes of other errors that are worth retrying.
+	sqlStateTooManyConnections   = "53300"
+	sqlStateAdminShutdown        = "57P01"
+	sqlStateCrashShutdown        = "57P02"
+	sqlStateCannotConnectNow     = "57P03"
+	sqlStateSerializationFailure = "40001"
+)
+
+// isTransientError reports whether the error is caused by a dropped or unavailable connection,
+// or by a server that is restarting or overloaded, so the statement may succeed on a new connection.
+// Other errors, e.g. syntax errors, constraint violations and values that can't be converted, are permanent.
+func isTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// statements that may have been applied already must not run twice
+	var unknownOutcomeErr *unknownOutcomeError
+	if errors.As(err, &unknownOutcomeErr) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case sqlStateTooManyConnections, sqlStateAdminShutdown, sqlStateCrashShutdown,
+			sqlStateCannotConnectNow, sqlStateSerializationFailure:
+			return true
+		default:
+			return strings.HasPrefix(pgErr.Code, sqlStateClassConnectionException)
+		}
+	}
+
+	// errors that occurred before anything was sent to the server
+	if safeToRetry(err) {
+		return true
+	}
+
+	// failures to connect and connections dropped by the network
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+// safeToRetry reports whether the error, or an error it wraps, is guaranteed to have occurred
+// before anything was sent to the server, see pgconn.SafeToRetry.
+func safeToRetry(err error) bool {
+	var retryable interface{ SafeToRetry() bool }
+
+	return errors.As(err, &retryable) && retryable.SafeToRetry()
+}
+
+// unknownOutcomeError occurs when the connection fails while a statement that isn't safe to run twice,
+// e.g. an INSERT or a COPY, may have already been applied by the server.
+// It's not retried, since Materialize has no unique constraints, so running the statement again
+// could write the rows twice.
+type unknownOutcomeError struct {
+	err error
+}
+
+// Error returns the error message of the connection failure.
+func (e *unknownOutcomeError) Error() string {
+	return fmt.Sprintf("the statement may have been applied before the connection failed: %s", e.err)
+}
+
+// Unwrap returns the connection failure.
+func (e *unknownOutcomeError) Unwrap() error {
+	return e.err
+}
+
+// nonIdempotent returns the error of a statement that isn't safe to run twice, so that it's retried
+// only if the statement certainly wasn't applied: either it wasn't sent to the server at all,
+// or the server reported that it failed. Other transient errors are returned as an *unknownOutcomeError.
+func nonIdempotent(err error) error {
+	if !isTransientError(err) || safeToRetry(err) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return err
+	}
+
+	return &unknownOutcomeError{err: err}
+}
+
+// retry calls the function until it succeeds, fails with a permanent error, or the config.Config.MaxRetries run out.
+// Functions that run statements that aren't safe to run twice must return their errors through nonIdempotent.
+// Before every retry it waits with an exponential backoff, reconnects to Materialize
+// and re-fetches the column types of the table, which may have changed in the meantime.
+func (d *Destination) retry(ctx context.Context, tableName string, fn func() error) error {
+	err := fn()
+
+	backoff := d.config.RetryBackoff
+	for attempt := 1; attempt <= d.config.MaxRetries && isTransientError(err); attempt++ {
+		sdk.Logger(ctx).Warn().Err(redact(err, d.secrets)).
+			Str("table", tableName).
+			Int("attempt", attempt).
+			Dur("backoff", backoff).
+			Msg("retrying after a transient error")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry: %w)", err, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, maxRetryBackoff)
+
+		if err = d.reconnect(ctx, tableName); err != nil {
+			continue
+		}
+
+		err = fn()
+	}
+
+	if err != nil && isTransientError(err) && d.config.MaxRetries > 0 {
+		return fmt.Errorf("give up after %d retries: %w", d.config.MaxRetries, err)
+	}
+
+	return err
+}
+
+// reconnect makes sure the pool has a working connection and re-fetches the column types of the table.
+// The pool drops broken connections by itself, so pinging it establishes a new one if needed.
+func (d *Destination) reconnect(ctx context.Context, tableName string) error {
+	if err := d.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+
+	if _, err := d.columnTypes.Refresh(ctx, tableName); err != nil {
+		return fmt.Errorf("refresh column types: %w", err)
+	}
+
+	return nil
+}
+
+// retrying wraps the record handler, so that it's retried if it fails with a transient error, see retry.
+func (d *Destination) retrying(handle recordHandler) recordHandler {
+	return func(ctx context.Context, tableName string, record opencdc.Record) error {
+		return d.retry(ctx, tableName, func() error {
+			return handle(ctx, tableName, record)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/conduitio/conduit-connector-sdk/schema"
+	"github.com/hamba/avro/v2"
+)
+
+// ErrUnsupportedSchemaType occurs when a record's schema isn't an Avro schema.
+var ErrUnsupportedSchemaType = errors.New("unsupported schema type")
+
+// schemaCache caches schemas resolved through the schema service by their subjects and versions.
+// The zero value is ready to use, and it's safe for concurrent use.
+type schemaCache struct {
+	mu      sync.Mutex
+	schemas map[string]avro.Schema
+}
+
+// payloadSchema returns the Avro schema of the record's payload resolved through the schema service,
+// or nil if the record has no payload schema attached.
+func (d *Destination) payloadSchema(ctx context.Context, record opencdc.Record) (avro.Schema, error) {
+	sch, err := d.schemas.metadataSchema(ctx,
+		record.Metadata.GetPayloadSchemaSubject, record.Metadata.GetPayloadSchemaVersion)
+	if err != nil {
+		return nil, fmt.Errorf("get payload schema: %w", err)
+	}
+
+	return sch, nil
+}
+
+// keySchema returns the Avro schema of the record's key resolved through the schema service,
+// or nil if the record has no key schema attached.
+func (d *Destination) keySchema(ctx context.Context, record opencdc.Record) (avro.Schema, error) {
+	sch, err := d.schemas.metadataSchema(ctx,
+		record.Metadata.GetKeySchemaSubject, record.Metadata.GetKeySchemaVersion)
+	if err != nil {
+		return nil, fmt.Errorf("get key schema: %w", err)
+	}
+
+	return sch, nil
+}
+
+// metadataSchema returns the schema with the subject and the version taken from the record's metadata,
+// or nil if there is no subject in the metadata.
+func (c *schemaCache) metadataSchema(
+	ctx context.Context, getSubject func() (string, error), getVersion func() (int, error),
+) (avro.Schema, error) {
+	subject, err := getSubject()
+	if errors.Is(err, opencdc.ErrMetadataFieldNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get subject: %w", err)
+	}
+
+	version, err := getVersion()
+	if err != nil {
+		return nil, fmt.Errorf("get version: %w", err)
+	}
+
+	return c.get(ctx, subject, version)
+}
+
+// get returns the cached schema, fetching and parsing it if it's not cached yet.
+func (c *schemaCache) get(ctx context.Context, subject string, version int) (avro.Schema, error) {
+	cacheKey := subject + ":" + strconv.Itoa(version)
+
+	c.mu.Lock()
+	parsed, ok := c.schemas[cacheKey]
+	c.mu.Unlock()
+
+	if ok {
+		return parsed, nil
+	}
+
+	parsed, err := resolveSchema(ctx, subject, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.schemas == nil {
+		c.schemas = make(map[string]avro.Schema)
+	}
+	c.schemas[cacheKey] = parsed
+	c.mu.Unlock()
+
+	return parsed, nil
+}
+
+// resolveSchema fetches the schema from the schema service and parses it.
+func resolveSchema(ctx context.Context, subject string, version int) (avro.Schema, error) {
+	sch, err := schema.Get(ctx, subject, version)
+	if err != nil {
+		return nil, fmt.Errorf("get schema %q version %d: %w", subject, version, err)
+	}
+
+	if sch.Type != schema.TypeAvro {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSchemaType, sch.Type)
+	}
+
+	parsed, err := avro.ParseBytes(sch.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema %q version %d: %w", subject, version, err)
+	}
+
+	return parsed, nil
+}
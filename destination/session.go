@@ -0,0 +1,90 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/config"
+	"github.com/jackc/pgx/v4"
+)
+
+// searchPathSetting is the name of the session setting that holds a list of schemas.
+const searchPathSetting = "search_path"
+
+// applySessionSettings returns a function that sets the session settings of the config on a new connection.
+// It's used as the AfterConnect hook of the pool, so the settings apply to every connection,
+// including the ones that replace broken connections after a reconnect.
+func applySessionSettings(cfg config.Config) func(context.Context, *pgx.Conn) error {
+	names := cfg.SessionSettingNames()
+
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		for _, name := range names {
+			if _, err := conn.Exec(ctx, setStatement(name, cfg.SessionSettings[name])); err != nil {
+				return fmt.Errorf("set session setting %q: %w", name, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// setStatement returns the SET statement of the session setting.
+// The value of the search_path is a comma-separated list of schemas, which are set as separate literals.
+func setStatement(name, value string) string {
+	values := []string{value}
+	if name == searchPathSetting {
+		values = strings.Split(value, ",")
+	}
+
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literals[i] = quoteLiteral(strings.TrimSpace(value))
+	}
+
+	return fmt.Sprintf("SET %s = %s", name, strings.Join(literals, ", "))
+}
+
+// quoteLiteral quotes the value as a string literal.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// validateSessionSettings checks that the session settings took effect: acquiring a connection applies them
+// with SET, which fails for invalid values, and SHOW fails for settings that don't exist.
+// The values aren't compared with the output of SHOW, since Materialize reports equal values differently,
+// e.g. a statement_timeout of 60000 is shown as 1min.
+func (d *Destination) validateSessionSettings(ctx context.Context) error {
+	if len(d.config.SessionSettings) == 0 {
+		return nil
+	}
+
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	for _, name := range d.config.SessionSettingNames() {
+		var shown string
+		if err := conn.QueryRow(ctx, "SHOW "+name).Scan(&shown); err != nil {
+			return fmt.Errorf("show session setting %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
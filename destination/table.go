@@ -0,0 +1,112 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/coltypes"
+	"github.com/conduitio/conduit-commons/opencdc"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// tableTemplateData is the data the table template is evaluated against.
+type tableTemplateData struct {
+	Metadata opencdc.Metadata
+	Key      opencdc.StructuredData
+	Payload  opencdc.StructuredData
+}
+
+// getTableName returns either the table name found in the record's metadata
+// or the default configured value for table, rendered with the record if it's a template.
+//
+// The metadata keys are looked up in the order of config.Config.RoutingKeys,
+// names taken from the opencdc.collection key are transformed with config.Config.CollectionTable.
+func (d *Destination) getTableName(record opencdc.Record) (string, error) {
+	for _, metadataKey := range d.config.RoutingKeys() {
+		tableName := record.Metadata[metadataKey]
+		if tableName == "" {
+			continue
+		}
+
+		if metadataKey == opencdc.MetadataCollection {
+			tableName = d.config.CollectionTable(tableName)
+		}
+
+		return d.config.IdentifierCase.Apply(tableName), nil
+	}
+
+	if d.tableTemplate == nil {
+		if d.config.Table == "" {
+			return "", ErrEmptyTableName
+		}
+
+		return d.config.Table, nil
+	}
+
+	tableName, err := d.renderTableName(record)
+	if err != nil {
+		return "", fmt.Errorf("render table name: %w", err)
+	}
+
+	if tableName == "" {
+		return "", ErrEmptyTableName
+	}
+
+	return d.config.IdentifierCase.Apply(tableName), nil
+}
+
+// renderTableName evaluates the table template against the record's metadata, key and payload.
+// The key and payload are passed with their original field names.
+func (d *Destination) renderTableName(record opencdc.Record) (string, error) {
+	key, err := decodeData(record.Key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key: %w", err)
+	}
+
+	payload, err := decodeData(record.Payload.After)
+	if err != nil {
+		return "", fmt.Errorf("failed to get payload: %w", err)
+	}
+
+	var sb strings.Builder
+	err = d.tableTemplate.Execute(&sb, tableTemplateData{
+		Metadata: record.Metadata,
+		Key:      key,
+		Payload:  payload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("execute template: %w", err)
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// tableIdentifier returns the goqu identifier of a table, schema.table or database.schema.table name.
+func tableIdentifier(tableName string) (exp.IdentifierExpression, error) {
+	name, err := coltypes.ParseTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if name.Database != "" {
+		// goqu identifiers have no database part, so the parts are shifted by one to render all three of them
+		return goqu.S(name.Database).Table(name.Schema).Col(name.Name), nil
+	}
+
+	return goqu.S(name.Schema).Table(name.Name), nil
+}
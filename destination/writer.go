@@ -0,0 +1,271 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/conduitio-labs/conduit-connector-materialize/config"
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// tableRecords are the records routed to a single table, in the order they're received.
+type tableRecords struct {
+	name    string
+	records []opencdc.Record
+	// indexes are the indexes of the records within the written records.
+	indexes []int
+}
+
+// recordHandler writes a record to the table, the name of which is resolved beforehand, see groupByTable.
+type recordHandler func(ctx context.Context, tableName string, record opencdc.Record) error
+
+// forTable binds the handler to the table, so that it can be passed to sdk.Util.Destination.Route.
+func (h recordHandler) forTable(tableName string) func(context.Context, opencdc.Record) error {
+	return func(ctx context.Context, record opencdc.Record) error {
+		return h(ctx, tableName, record)
+	}
+}
+
+// errPrecedingRecordFailed occurs when a record isn't written because a preceding record of another table failed.
+var errPrecedingRecordFailed = errors.New("a preceding record failed")
+
+// writeProgress tracks the records written by all the table writers and the first of the records that failed.
+type writeProgress struct {
+	mu sync.Mutex
+	// changed is signaled whenever records are written or fail.
+	changed *sync.Cond
+	// written holds the indexes of the written records that follow the watermark.
+	written map[int]bool
+	// watermark is the number of leading records that are all written.
+	watermark int
+	// index and err are the index and the error of the first failed record, err is nil if no record has failed.
+	index int
+	err   error
+}
+
+// newWriteProgress returns a writeProgress of records none of which is written yet.
+func newWriteProgress() *writeProgress {
+	p := &writeProgress{written: make(map[int]bool)}
+	p.changed = sync.NewCond(&p.mu)
+
+	return p
+}
+
+// set records the failure of the record with the index, unless a preceding record has already failed.
+func (p *writeProgress) set(index int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err == nil || index < p.index {
+		p.index = index
+		p.err = err
+	}
+
+	p.changed.Broadcast()
+}
+
+// done marks the records with the indexes as written.
+func (p *writeProgress) done(indexes ...int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, index := range indexes {
+		p.written[index] = true
+	}
+
+	for p.written[p.watermark] {
+		delete(p.written, p.watermark)
+		p.watermark++
+	}
+
+	p.changed.Broadcast()
+}
+
+// allows reports whether the record with the index precedes the first failed record,
+// so it's still worth writing.
+func (p *writeProgress) allows(index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err == nil || index < p.index
+}
+
+// waitPreceding waits until all the records preceding the index are written.
+// It returns errPrecedingRecordFailed if one of them fails, or the context's error if it's done first.
+func (p *writeProgress) waitPreceding(ctx context.Context, index int) error {
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.changed.Broadcast()
+	})
+	defer stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.watermark < index {
+		if p.err != nil && p.index < index {
+			return errPrecedingRecordFailed
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p.changed.Wait()
+	}
+
+	return nil
+}
+
+// groupByTable groups the records by their tables, keeping the order of the tables and the records.
+// If the table of a record can't be determined, the failure is recorded and the following records are left out.
+func (d *Destination) groupByTable(records []opencdc.Record, progress *writeProgress) []*tableRecords {
+	var tables []*tableRecords
+
+	byName := make(map[string]*tableRecords)
+	for i, record := range records {
+		tableName, err := d.getTableName(record)
+		if err != nil {
+			progress.set(i, recordError(record, fmt.Errorf("route %s: %w", record.Operation.String(), err)))
+
+			break
+		}
+
+		table, ok := byName[tableName]
+		if !ok {
+			table = &tableRecords{name: tableName}
+			byName[tableName] = table
+			tables = append(tables, table)
+		}
+
+		table.records = append(table.records, record)
+		table.indexes = append(table.indexes, i)
+	}
+
+	return tables
+}
+
+// writeTable writes the records of a single table in order. It stops at the first record that fails,
+// or at a record following a record of another table that has failed, see writeProgress.
+//
+// Consecutive creates with the same set of columns are collected into a batch
+// and written with a single multi-row INSERT statement.
+// Consecutive snapshots are collected the same way and bulk-loaded with COPY FROM STDIN.
+// Rows inserted by a batch would be duplicated if the records were redelivered,
+// so a batch holds records that are adjacent among all the written records, and it's written
+// only once all the preceding records are written. That way, if a record fails,
+// none of the records following it is inserted, and Write reports an accurate count.
+// Other records, e.g. updates and deletes, are written without waiting,
+// since writing them once again has no effect.
+//
+// In the config.WriteModeReplace mode records are written one by one, see Destination.replace.
+// Statements that fail with transient errors are retried, see Destination.retry.
+func (d *Destination) writeTable(ctx context.Context, table *tableRecords, progress *writeProgress) {
+	var batch insertBatch
+
+	var handleCreate, handleUpdate recordHandler = d.insert, d.update
+	if d.config.WriteMode == config.WriteModeReplace {
+		handleCreate, handleUpdate = d.replace, d.replace
+	}
+
+	createRecord := d.retrying(d.refreshingColumnTypes(handleCreate)).forTable(table.name)
+	updateRecord := d.retrying(d.refreshingColumnTypes(handleUpdate)).forTable(table.name)
+	deleteRecord := d.retrying(d.refreshingColumnTypes(d.delete)).forTable(table.name)
+
+	// flush writes the batch once the preceding records are written and reports whether it succeeded
+	flush := func() bool {
+		if len(batch.rows) == 0 {
+			return true
+		}
+
+		if err := progress.waitPreceding(ctx, batch.start); err != nil {
+			progress.set(batch.start, err)
+
+			return false
+		}
+
+		indexes := batch.indexes
+		if index, err := d.flush(ctx, &batch); err != nil {
+			progress.set(index, err)
+
+			return false
+		}
+
+		progress.done(indexes...)
+
+		return true
+	}
+
+	for i, record := range table.records {
+		index := table.indexes[i]
+		if !progress.allows(index) {
+			break
+		}
+
+		if d.config.WriteMode != config.WriteModeReplace &&
+			(record.Operation == opencdc.OperationCreate || record.Operation == opencdc.OperationSnapshot) {
+			// preparing the row may query the column types, create the table or add columns
+			var row insertRow
+			err := d.retry(ctx, table.name, func() (err error) {
+				row, err = d.prepareInsert(ctx, table.name, record)
+
+				return err
+			})
+			if err != nil {
+				// the records collected so far are valid, so write them before reporting the error
+				if flush() {
+					progress.set(index, recordError(record, fmt.Errorf("route %s: %w", record.Operation.String(), err)))
+				}
+
+				return
+			}
+
+			if (!batch.accepts(row) || !batch.adjoins(index)) && !flush() {
+				return
+			}
+
+			batch.add(index, record, row)
+
+			continue
+		}
+
+		if !flush() {
+			return
+		}
+
+		err := sdk.Util.Destination.Route(ctx, record,
+			createRecord,
+			updateRecord,
+			deleteRecord,
+			createRecord,
+		)
+		if err != nil {
+			progress.set(index, recordError(record, fmt.Errorf("route %s: %w", record.Operation.String(), err)))
+
+			return
+		}
+
+		progress.done(index)
+	}
+
+	flush()
+}
@@ -18,9 +18,16 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+// Execer executes SQL statements, it's implemented by both a connection and a pool of connections.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
 // SetupTestConnection connects to a database and returns the connection.
 func SetupTestConnection(dsn string) (*pgx.Conn, error) {
 	conn, err := pgx.Connect(context.Background(), dsn)
@@ -35,8 +42,24 @@ func SetupTestConnection(dsn string) (*pgx.Conn, error) {
 	return conn, nil
 }
 
+// SetupTestPool connects a pool of connections to a database and returns the pool.
+func SetupTestPool(dsn string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.Connect(context.Background(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to materialize: %w", err)
+	}
+
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+
+		return nil, fmt.Errorf("failed to ping materialize: %w", err)
+	}
+
+	return pool, nil
+}
+
 // MigrateTestDB creates a table with a name of the tableName argument.
-func MigrateTestDB(ctx context.Context, conn *pgx.Conn, tableName string) error {
+func MigrateTestDB(ctx context.Context, conn Execer, tableName string) error {
 	_, err := conn.Exec(ctx, fmt.Sprintf(`
 		create table if not exists %s (
 			id int,